@@ -0,0 +1,183 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+
+	helper "github.com/CB2Moon/DJI_RC_Nx_Translator/pkg"
+	"go.bug.st/serial/enumerator"
+)
+
+var (
+	rcIdentity       string
+	calibration      helper.Calibration
+	wizard           *helper.CalibrationWizard
+	autoCalibrator   *helper.AutoCalibrator
+	calibrationMutex sync.Mutex // guards rcIdentity, calibration, wizard and autoCalibrator
+)
+
+// deviceIdentity identifies an RC by its USB IDs and serial number
+func deviceIdentity(port *enumerator.PortDetails) string {
+	return fmt.Sprintf("%s:%s:%s", port.VID, port.PID, port.SerialNumber)
+}
+
+// useDeviceCalibration loads the stored calibration of an RC, or the nominal range if there is none
+func useDeviceCalibration(identity string) {
+	store, err := helper.LoadCalibrationStore(*calibrationFile)
+	if err != nil {
+		uiLogger("Error loading calibration: %v", err)
+		store = &helper.CalibrationStore{Devices: map[string]helper.Calibration{}}
+	}
+
+	cal := store.Get(identity)
+	if cal == nil {
+		uiLogger("No calibration stored for RC %s, using nominal range", identity)
+	} else {
+		uiLogger("Loaded calibration for RC %s: %v", identity, cal)
+	}
+
+	var auto *helper.AutoCalibrator
+	if *autoCalibrate {
+		auto = helper.NewAutoCalibrator(cal, helper.KnownChannels, *driftThreshold)
+		uiLogger("Auto-calibration enabled, ranges widen as the sticks travel further")
+	}
+
+	calibrationMutex.Lock()
+	rcIdentity = identity
+	calibration = cal
+	autoCalibrator = auto
+	calibrationMutex.Unlock()
+}
+
+// currentCalibration returns the calibration applied to the active RC
+func currentCalibration() helper.Calibration {
+	calibrationMutex.Lock()
+	defer calibrationMutex.Unlock()
+	return calibration
+}
+
+// currentAutoCalibrator returns the auto-calibrator, nil if auto-calibration is off
+func currentAutoCalibrator() *helper.AutoCalibrator {
+	calibrationMutex.Lock()
+	defer calibrationMutex.Unlock()
+	return autoCalibrator
+}
+
+// currentWizard returns the running calibration wizard, nil if none is running
+func currentWizard() *helper.CalibrationWizard {
+	calibrationMutex.Lock()
+	defer calibrationMutex.Unlock()
+	return wizard
+}
+
+// setCalibButtonText updates the calibration button from any goroutine
+func setCalibButtonText(text string) {
+	if mainWindow != nil {
+		mainWindow.Synchronize(func() {
+			if calibButton != nil {
+				calibButton.SetText(text)
+			}
+		})
+	}
+}
+
+// advanceCalibration moves the calibration wizard one step forward:
+// start recording extremes, then record the rest position, then save
+func advanceCalibration() {
+	w := currentWizard()
+	if w == nil {
+		if gamepad == nil {
+			uiLogger("Start the translator before calibrating.")
+			return
+		}
+		calibrationMutex.Lock()
+		wizard = helper.NewCalibrationWizard(calibration)
+		calibrationMutex.Unlock()
+
+		uiLogger("Calibration: move both sticks and the camera dial to all their extremes a few times, then click \"Center\".")
+		setCalibButtonText("Center")
+		return
+	}
+
+	switch w.Step() {
+	case helper.CalibrationExtremes:
+		if err := w.Next(); err != nil {
+			uiLogger("Calibration: %v", err)
+			return
+		}
+		uiLogger("Calibration: recorded ranges %s", w.Ranges())
+		uiLogger("Calibration: release the sticks and the dial to rest, then click \"Save\".")
+		setCalibButtonText("Save")
+
+	case helper.CalibrationCenter:
+		cal, skipped, err := w.Finish()
+		if err != nil {
+			uiLogger("Calibration: %v", err)
+			return
+		}
+		if len(skipped) > 0 {
+			uiLogger("Calibration: range too small, keeping previous values for %v", skipped)
+		}
+		saveCalibration(cal)
+		cancelCalibration()
+	}
+}
+
+// saveCalibration applies a calibration to the active RC and persists it
+func saveCalibration(cal helper.Calibration) {
+	calibrationMutex.Lock()
+	calibration = cal
+	identity := rcIdentity
+	auto := autoCalibrator
+	calibrationMutex.Unlock()
+	uiLogger("Calibration applied: %v", cal)
+
+	if auto != nil {
+		auto.Rebase(cal)
+	}
+	if err := storeCalibration(identity, cal); err != nil {
+		uiLogger("Error saving calibration: %v", err)
+		return
+	}
+	uiLogger("Calibration saved for RC %s", identity)
+}
+
+// saveLearnedCalibration persists what auto-calibration learned during the session
+func saveLearnedCalibration() {
+	calibrationMutex.Lock()
+	auto := autoCalibrator
+	identity := rcIdentity
+	calibrationMutex.Unlock()
+	if auto == nil {
+		return
+	}
+
+	cal := auto.Calibration()
+	calibrationMutex.Lock()
+	calibration = cal
+	calibrationMutex.Unlock()
+
+	if err := storeCalibration(identity, cal); err != nil {
+		uiLogger("Error saving learned calibration: %v", err)
+		return
+	}
+	uiLogger("Learned calibration saved for RC %s: %v", identity, cal)
+}
+
+// storeCalibration writes the calibration of one RC into the calibration file
+func storeCalibration(identity string, cal helper.Calibration) error {
+	store, err := helper.LoadCalibrationStore(*calibrationFile)
+	if err != nil {
+		return err
+	}
+	store.Set(identity, cal)
+	return store.Save(*calibrationFile)
+}
+
+// cancelCalibration stops a running calibration wizard
+func cancelCalibration() {
+	calibrationMutex.Lock()
+	wizard = nil
+	calibrationMutex.Unlock()
+	setCalibButtonText("Calibrate")
+}
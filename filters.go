@@ -0,0 +1,27 @@
+package main
+
+import (
+	"sort"
+	"time"
+
+	helper "github.com/CB2Moon/DJI_RC_Nx_Translator/pkg"
+)
+
+// logFilterDelays lists a profile's smoothing filters with the delay each adds at the measured
+// frame interval. Before frames were measured, delays counted in frames are logged as such.
+func logFilterDelays(p *helper.Profile, indent string) {
+	names := make([]string, 0, len(p.Filters))
+	for name := range p.Filters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	interval := latency.FrameInterval()
+	for _, name := range names {
+		f := p.Filters[name]
+		if frames := f.DelayFrames(); frames > 0 && interval == 0 {
+			uiLogger("%sFilter %s: %v, adds about %d frame(s) delay", indent, name, f, frames)
+			continue
+		}
+		uiLogger("%sFilter %s: %v, adds about %v delay", indent, name, f, f.Delay(interval).Round(100*time.Microsecond))
+	}
+}
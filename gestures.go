@@ -0,0 +1,61 @@
+package main
+
+import (
+	"maps"
+	"time"
+
+	helper "github.com/CB2Moon/DJI_RC_Nx_Translator/pkg"
+)
+
+// recenterDelay gives the pilot time to let go of the sticks after a recenter gesture
+const recenterDelay = 2 * time.Second
+
+// runGestureAction carries out the parts of a recognized gesture's action the mapper leaves
+// to the translator
+func runGestureAction(g helper.GestureConfig) {
+	uiLogger("Gesture %q: %v", g.Name, g.Action)
+
+	if name := g.Action.Profile; name != "" {
+		if err := selectProfile(name); err != nil {
+			uiLogger("Error: %v", err)
+		} else if mainWindow != nil {
+			mainWindow.Synchronize(syncProfileBox)
+		}
+	}
+	if g.Action.Recenter {
+		uiLogger("Release the sticks, recentering in %v", recenterDelay)
+		time.AfterFunc(recenterDelay, recenterCalibration)
+	}
+}
+
+// recenterCalibration takes the current raw stick positions as the new centers
+func recenterCalibration() {
+	stateMutex.Lock()
+	raw := make(map[string]uint16, len(rawPositions))
+	for name, v := range rawPositions {
+		raw[name] = v
+	}
+	stateMutex.Unlock()
+	if len(raw) == 0 {
+		uiLogger("No RC data to recenter on")
+		return
+	}
+
+	base := currentCalibration()
+	if auto := currentAutoCalibrator(); auto != nil {
+		base = auto.Calibration()
+	}
+	// Only the stick centers move; ranges and the camera dial keep their calibration
+	cal := maps.Clone(base)
+	if cal == nil {
+		cal = make(helper.Calibration)
+	}
+	for _, name := range helper.DefaultAxisChannels {
+		cc := base.Channel(name)
+		if v, ok := raw[name]; ok && v > cc.Min && v < cc.Max {
+			cc.Center = v
+			cal[name] = cc
+		}
+	}
+	saveCalibration(cal)
+}
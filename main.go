@@ -2,14 +2,18 @@ package main
 
 import (
 	"encoding/binary"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"runtime"
+	"strings"
+	"sync"
 	"time"
 
 	helper "github.com/CB2Moon/DJI_RC_Nx_Translator/pkg"
-	"github.com/CB2Moon/vgamepad-go/pkg/commons"
 	"github.com/CB2Moon/vgamepad-go/pkg/vgamepad"
 	"github.com/lxn/walk"
 	. "github.com/lxn/walk/declarative"
@@ -17,22 +21,67 @@ import (
 	"go.bug.st/serial/enumerator"
 )
 
+// Command line options
+var (
+	pollRate     = flag.Float64("poll-rate", 100, "Target channel poll rate in Hz (0 = as fast as the RC replies)")
+	pollInFlight = flag.Int("inflight", 1, "Number of channel requests kept in flight (1 or 2)")
+	readTimeout  = flag.Duration("read-timeout", 50*time.Millisecond, "Serial read deadline")
+	stallTimeout = flag.Duration("stall-timeout", 500*time.Millisecond, "Report a stall and recover after this long without a reply")
+
+	failsafeMode     = flag.String("failsafe", "center", "Output when RC data goes stale: hold, center, throttle-low or custom")
+	failsafeTimeout  = flag.Duration("failsafe-timeout", 250*time.Millisecond, "Trigger the failsafe after this long without fresh RC data")
+	failsafeRecovery = flag.Duration("failsafe-recovery", 300*time.Millisecond, "Time to glide back to live values once data resumes")
+	failsafeValues   = flag.String("failsafe-values", "", "Channel values for custom failsafe, e.g. left_vertical=-32768,camera_dial=0")
+
+	stickModeName = flag.String("stick-mode", "2", "Stick layout 1 to 4 set on the RC")
+
+	outputRate = flag.Float64("output-rate", 0, "Gamepad update rate in Hz, estimating stick values between RC frames (0 = on every RC frame without estimation)")
+	outputMode = flag.String("output-mode", "interpolate", "Estimation between RC frames: interpolate (one frame behind) or extrapolate (along the stick's velocity)")
+
+	profileName  = flag.String("profile", defaultProfileName, "Mapping profile name or path to a profile JSON file")
+	profilesDir  = flag.String("profiles-dir", "profiles", "Directory with mapping profile JSON files")
+	ratesFile    = flag.String("import-rates", "", "Apply rates from a file of Betaflight CLI \"set\" lines to the selected profile")
+	ratesMaxRate = flag.Float64("rates-max", 0, "Simulator full-deflection rate in deg/s for imported rates (0 = scale each curve to full deflection)")
+
+	calibrationFile = flag.String("calibration-file", "calibration.json", "File storing stick calibrations per RC")
+	autoCalibrate   = flag.Bool("auto-calibrate", false, "Learn stick ranges and centers while flying and keep them between sessions")
+	driftThreshold  = flag.Int("drift-threshold", 25, "Warn when a stick's rest position drifts this many raw units from its calibrated center")
+)
+
+// pollReplyTimeout is how long a channel request may stay unanswered before it is dropped
+const pollReplyTimeout = 500 * time.Millisecond
+
 // Global variables
 var (
 	sequenceNumber uint16 = 0x34eb
-	stickPositions        = map[string]int16{"right_horizontal": 0, "right_vertical": 0, "left_horizontal": 0, "left_vertical": 0}
-	cameraDial     int16  = 0
+	stickPositions        = map[string]int16{"right_horizontal": 0, "right_vertical": 0, "left_horizontal": 0, "left_vertical": 0, "camera_dial": 0}
+	rawPositions          = make(map[string]uint16)
+	frameStamps    helper.FrameStamps
+	stateMutex     sync.Mutex               // guards stickPositions, rawPositions and frameStamps
+	frameReady     = make(chan struct{}, 1) // signals new stick positions to the gamepad loop
+	latency        = helper.NewLatencyTracker()
+	pollScheduler  *helper.PollScheduler
+	stallDetector  *helper.StallDetector
+	failsafe       *helper.Failsafe
+	upsampleMode   helper.UpsampleMode
 	serialPort     serial.Port
+	serialPortName string
+	portMutex      sync.Mutex // guards serialPort while it may be reopened
 	stopChan       = make(chan bool)
 	gamepad        *vgamepad.VX360Gamepad
 
 	// UI related globals
-	mainWindow  *walk.MainWindow
-	logView     *walk.TextEdit
-	statusLabel *walk.Label
-	startButton *walk.PushButton
-	stopButton  *walk.PushButton
-	exitButton  *walk.PushButton
+	mainWindow   *walk.MainWindow
+	logView      *walk.TextEdit
+	statusLabel  *walk.Label
+	profileBox   *walk.ComboBox
+	startButton  *walk.PushButton
+	stopButton   *walk.PushButton
+	statsButton  *walk.PushButton
+	calibButton  *walk.PushButton
+	curvesButton *walk.PushButton
+	ratesButton  *walk.PushButton
+	exitButton   *walk.PushButton
 )
 
 // Logger function that redirects log output to UI
@@ -65,90 +114,224 @@ func updateStatus(status string) {
 }
 
 // sendDUML sends a DUML (DJI Universal Markup Language) protocol packet over serial port.
-func sendDUML(port serial.Port, sourceAddress, targetAddress, commandType, commandSet, commandID byte, payload []byte) error {
+// It returns the sequence number the packet was sent with.
+func sendDUML(port serial.Port, sourceAddress, targetAddress, commandType, commandSet, commandID byte, payload []byte) (uint16, error) {
 	if port == nil {
-		return fmt.Errorf("serial port is nil")
+		return 0, fmt.Errorf("serial port is nil")
 	}
 
-	packet, err := helper.BuildDUML(sequenceNumber, sourceAddress, targetAddress, commandType, commandSet, commandID, payload)
+	seq := sequenceNumber
+	packet, err := helper.BuildDUML(seq, sourceAddress, targetAddress, commandType, commandSet, commandID, payload)
 	if err != nil {
-		return err
+		return 0, err
 	}
 
 	if _, err = port.Write(packet); err != nil {
-		return err
+		return 0, err
 	}
 
 	sequenceNumber++
-	return nil
+	return seq, nil
 }
 
-// parseInput converts RC-Nx (364 to 1024 to 1684) stick input values to Xbox controller range (-32768 to 0 to 32767)
-func parseInput(rawInput []byte) int16 {
-	value := int(binary.LittleEndian.Uint16(rawInput)) - 1024
-	mappedValue := value * 2 * 4096 / 165
-	if mappedValue >= 32768 {
-		mappedValue = 32767
-	}
-	return int16(mappedValue)
+// parseInput converts a raw RC-Nx stick value (nominally 364 to 1024 to 1684) to Xbox controller range
+// (-32768 to 0 to 32767) using the channel's calibration
+func parseInput(rawInput uint16, cal helper.ChannelCalibration) int16 {
+	return cal.Normalize(rawInput)
 }
 
-// getStickStatus returns the stick status if the packet is valid
-func getStickStatus(packet []byte) (rightHorizontal, rightVertical, leftVertical, leftHorizontal, cameraDial int16, err error) {
+// getStickStatus stores the raw stick values in raw if the packet is valid
+func getStickStatus(packet []byte, raw map[string]uint16) error {
 	if err := helper.ValidatePacket(packet); err != nil {
-		return 0, 0, 0, 0, 0, err
+		return err
 	}
 
-	return parseInput(packet[13:15]), parseInput(packet[16:18]), parseInput(packet[19:21]), parseInput(packet[22:24]), parseInput(packet[25:27]), nil
+	helper.DecodeRawChannels(packet, raw)
+	return nil
 }
 
+// idleUpdateInterval is how often the gamepad is updated while no RC frames arrive
+const idleUpdateInterval = 100 * time.Millisecond
+
+// maxOutputRate is the highest -output-rate accepted, in Hz
+const maxOutputRate = 1000
+
 // translateN1MovementAndUpdateGamepad continuously updates virtual gamepad state
 func translateN1MovementAndUpdateGamepad() {
 	uiLogger("Gamepad update loop started.")
+
+	// Without upsampling the gamepad is updated on every RC frame, so button rules, gestures
+	// and macros are timed at the RC's rate. The tick keeps the failsafe and timed outputs
+	// running while no frames arrive.
+	interval := idleUpdateInterval
+	frames := frameReady
+	var upsampler *helper.Upsampler
+	if *outputRate > 0 {
+		interval = time.Duration(float64(time.Second) / *outputRate)
+		frames = nil
+		upsampler = helper.NewUpsampler(upsampleMode)
+		uiLogger("Updating the gamepad at %.0f Hz (%v)", *outputRate, upsampleMode)
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var lastReply, lastFrame time.Time
+	failsafeState := helper.FailsafeInactive
+	menuMode := false
+	macro := ""
+	rateSet := ""
 	for {
 		select {
 		case <-stopChan:
 			uiLogger("Gamepad update loop stopped.")
 			return
-		default:
-			time.Sleep(100 * time.Millisecond)
+		case <-frames:
+			// The tick only covers gaps between frames
+			ticker.Reset(interval)
+		case <-ticker.C:
+		}
 
-			if gamepad == nil {
-				continue
+		if gamepad == nil {
+			continue
+		}
+
+		stateMutex.Lock()
+		channels := make(map[string]int16, len(stickPositions))
+		for name, v := range stickPositions {
+			channels[name] = v
+		}
+		stamps := frameStamps
+		stateMutex.Unlock()
+
+		if upsampler != nil {
+			if !stamps.ReplyComplete.Equal(lastFrame) {
+				upsampler.Push(stamps.ReplyComplete, channels)
+				lastFrame = stamps.ReplyComplete
 			}
+			upsampler.Sample(time.Now(), channels)
+		}
 
-			gamepad.LeftJoystick(stickPositions["left_horizontal"], stickPositions["left_vertical"])
-			gamepad.RightJoystick(stickPositions["right_horizontal"], stickPositions["right_vertical"])
-
-			// leftH := stickPositions["left_horizontal"]
-			// leftV := stickPositions["left_vertical"]
-			// rightH := stickPositions["right_horizontal"]
-			// rightV := stickPositions["right_vertical"]
-
-			// log.Printf("Left: (%d, %d), Right: (%d, %d), Camera: %d\n",
-			// 	leftH, leftV, rightH, rightV, cameraDial)
-
-			if cameraDial > 32000 {
-				// log.Println("Pressing Y button (restart race)")
-				gamepad.PressButton(commons.XUSB_GAMEPAD_Y)
-			} else if cameraDial < -32000 {
-				// log.Println("Pressing B button (recover drone)")
-				gamepad.PressButton(commons.XUSB_GAMEPAD_B)
-			} else {
-				// log.Println("Releasing buttons")
-				gamepad.ReleaseButton(commons.XUSB_GAMEPAD_Y)
-				gamepad.ReleaseButton(commons.XUSB_GAMEPAD_B)
+		currentStickMode().Remap(channels)
+
+		mapper := currentMapper()
+		if failsafe.Stale(time.Now()) {
+			// Held values are not the pilot's input, so no gesture may fire or build up on them
+			mapper.ResetGestures()
+		} else {
+			for _, g := range mapper.Recognize(time.Now(), channels) {
+				runGestureAction(g)
 			}
+		}
+		if changed := mapper.Trim(time.Now(), channels); len(changed) > 0 {
+			reportTrims(mapper, changed)
+			scheduleTrimSave(mapper)
+		}
+		mapper.Shape(channels)
+		mapper.Limit(time.Now(), channels)
+
+		state, stale := failsafe.Apply(time.Now(), channels)
+		if state != failsafeState {
+			reportFailsafe(state, stale)
+			failsafeState = state
+			if state != helper.FailsafeInactive {
+				// Never replay a macro over failsafe outputs
+				mapper.CancelMacro()
+			}
+		}
 
-			if err := gamepad.Update(); err != nil {
-				uiLogger("Error updating gamepad state: %v", err)
+		applyGamepadState(gamepad, mapper.Map(time.Now(), channels))
+		if mapper.MenuMode() != menuMode {
+			menuMode = mapper.MenuMode()
+			uiLogger("Switched to %s", modeName(menuMode))
+			if failsafeState == helper.FailsafeInactive {
+				updateStatus(runningStatus())
+			}
+		}
+		if mapper.RateSet() != rateSet {
+			rateSet = mapper.RateSet()
+			if rateSet != "" {
+				uiLogger("Switched to %s rates", rateSet)
+			}
+			if failsafeState == helper.FailsafeInactive {
+				updateStatus(runningStatus())
+			}
+		}
+		if mapper.Macro() != macro {
+			if macro != "" {
+				uiLogger("Macro %q ended", macro)
+			}
+			macro = mapper.Macro()
+			if macro != "" {
+				uiLogger("Macro %q started", macro)
 			}
 		}
+		stamps.Mapped = time.Now()
+
+		if err := gamepad.Update(); err != nil {
+			uiLogger("Error updating gamepad state: %v", err)
+			continue
+		}
+		stamps.SinkUpdated = time.Now()
+
+		// Only the first update carrying a reply counts towards its pipeline latency
+		if !stamps.ReplyComplete.Equal(lastReply) {
+			latency.RecordPipeline(stamps)
+			lastReply = stamps.ReplyComplete
+		}
+	}
+}
+
+// reportFailsafe shows a failsafe state change in the log and status label
+func reportFailsafe(state helper.FailsafeState, stale time.Duration) {
+	switch state {
+	case helper.FailsafeActive:
+		uiLogger("Failsafe (%v): no RC data for %d ms", failsafe.Mode(), stale.Milliseconds())
+		updateStatus(fmt.Sprintf("Failsafe (%v) - no RC data", failsafe.Mode()))
+	case helper.FailsafeRecovering:
+		uiLogger("RC data resumed, leaving failsafe")
+		updateStatus("Running - leaving failsafe")
+	case helper.FailsafeInactive:
+		updateStatus(runningStatus())
+	}
+}
+
+// newFailsafe creates the failsafe from the command line options
+func newFailsafe() (*helper.Failsafe, error) {
+	mode, err := helper.ParseFailsafeMode(*failsafeMode)
+	if err != nil {
+		return nil, err
+	}
+	values, err := helper.ParseChannelValues(*failsafeValues)
+	if err != nil {
+		return nil, err
 	}
+
+	fs := helper.NewFailsafe(mode, *failsafeTimeout, *failsafeRecovery)
+	fs.SetCustomValues(values)
+	return fs, nil
 }
 
 // startControllerProcess starts the main controller processing
 func startControllerProcess() error {
+	fs, err := newFailsafe()
+	if err != nil {
+		return fmt.Errorf("invalid failsafe settings: %w", err)
+	}
+	failsafe = fs
+
+	mode, err := helper.ParseStickMode(*stickModeName)
+	if err != nil {
+		return err
+	}
+	useStickMode(mode)
+
+	if upsampleMode, err = helper.ParseUpsampleMode(*outputMode); err != nil {
+		return err
+	}
+	if *outputRate < 0 || *outputRate > maxOutputRate {
+		return fmt.Errorf("output rate %g Hz outside 0 to %d", *outputRate, maxOutputRate)
+	}
+
 	// a test gamepad to ensure ViGEmBus is installed
 	updateStatus("Initializing - Checking driver...")
 	uiLogger("Checking ViGEmBus driver installation...")
@@ -175,6 +358,7 @@ func startControllerProcess() error {
 		if port.IsUSB && port.Product == "DJI USB VCOM For Protocol" {
 			uiLogger("Found DJI USB VCOM For Protocol on %s", port.Name)
 			portName = port.Name
+			useDeviceCalibration(deviceIdentity(port))
 			foundPort = true
 			break
 		}
@@ -189,21 +373,18 @@ func startControllerProcess() error {
 	}
 
 	uiLogger("Opening serial port: %s", portName)
-	mode := &serial.Mode{
-		BaudRate: 115200,
-	}
-	port, err := serial.Open(portName, mode)
+	port, err := openSerialPort(portName)
 	if err != nil {
 		updateStatus("Failed to open serial port")
 		return fmt.Errorf("could not open serial port: %v", err)
 	}
-	serialPort = port
+	setSerialPort(port)
+	serialPortName = portName
 
 	uiLogger("Creating Virtual X360 Gamepad...")
 	gp, err := vgamepad.NewVX360Gamepad()
 	if err != nil {
-		serialPort.Close()
-		serialPort = nil
+		closeSerialPort()
 		updateStatus("Failed to create virtual gamepad")
 		uiLogger("Error creating gamepad: %v", err)
 		return fmt.Errorf("could not create gamepad: %w", err)
@@ -213,21 +394,163 @@ func startControllerProcess() error {
 	uiLogger("Virtual gamepad created successfully.")
 
 	uiLogger("Starting translator process...")
-	updateStatus("Running")
+	updateStatus(runningStatus())
+	latency.Reset()
+	pollScheduler = helper.NewPollScheduler(*pollRate, *pollInFlight)
+	stallDetector = helper.NewStallDetector(*stallTimeout)
 	safeGoroutine("GamepadUpdate", translateN1MovementAndUpdateGamepad)
 	safeGoroutine("SerialReadLoop", serialReadLoop)
+	safeGoroutine("PollLoop", pollLoop)
 
 	return nil
 }
 
-// serialReadLoop's original version
-func serialReadLoop() {
-	// Enable simulator mode for RC to get faster stick position updates
-	if err := sendDUML(serialPort, 0x0a, 0x06, 0x40, 0x06, 0x24, []byte{0x01}); err != nil {
+// openSerialPort opens the RC's serial port with the configured read deadline
+func openSerialPort(name string) (serial.Port, error) {
+	mode := &serial.Mode{
+		BaudRate: 115200,
+	}
+	port, err := serial.Open(name, mode)
+	if err != nil {
+		return nil, err
+	}
+	if err := port.SetReadTimeout(*readTimeout); err != nil {
+		port.Close()
+		return nil, fmt.Errorf("could not set read timeout: %w", err)
+	}
+	return port, nil
+}
+
+// currentPort returns the serial port in use, nil once it has been closed
+func currentPort() serial.Port {
+	portMutex.Lock()
+	defer portMutex.Unlock()
+	return serialPort
+}
+
+// setSerialPort replaces the serial port in use
+func setSerialPort(port serial.Port) {
+	portMutex.Lock()
+	defer portMutex.Unlock()
+	serialPort = port
+}
+
+// closeSerialPort closes the serial port in use, if any
+func closeSerialPort() error {
+	portMutex.Lock()
+	defer portMutex.Unlock()
+	if serialPort == nil {
+		return nil
+	}
+	err := serialPort.Close()
+	serialPort = nil
+	return err
+}
+
+// enableSimulatorMode enables simulator mode for RC to get faster stick position updates
+func enableSimulatorMode(port serial.Port) error {
+	_, err := sendDUML(port, 0x0a, 0x06, 0x40, 0x06, 0x24, []byte{0x01})
+	return err
+}
+
+// recoverStalledRC tries to get a silent RC replying again: it drops outstanding
+// requests and stale input, re-enables simulator mode and reopens the port if writing fails
+func recoverStalledRC(silence time.Duration) {
+	uiLogger("No reply from RC for %d ms, recovering...", silence.Milliseconds())
+	updateStatus(fmt.Sprintf("Stalled - no reply for %d ms", silence.Milliseconds()))
+
+	pollScheduler.Reset()
+	port := currentPort()
+	if port == nil {
+		return
+	}
+	if err := port.ResetInputBuffer(); err == nil {
+		if err = enableSimulatorMode(port); err == nil {
+			return
+		}
+	}
+
+	uiLogger("Reopening serial port %s...", serialPortName)
+	portMutex.Lock()
+	defer portMutex.Unlock()
+	if serialPort == nil {
+		// Stopped meanwhile
+		return
+	}
+	serialPort.Close()
+	newPort, err := openSerialPort(serialPortName)
+	if err != nil {
+		uiLogger("Could not reopen serial port: %v", err)
+		// Keep a closed port around so the next attempt retries instead of exiting the loops
+		return
+	}
+	serialPort = newPort
+	if err := enableSimulatorMode(newPort); err != nil {
 		uiLogger("Error sending DUML command: %v", err)
 	}
+}
 
-	buffer := make([]byte, 1024)
+// pollLoop requests channel values from the RC at the pace set by the poll scheduler.
+// Replies are consumed by serialReadLoop, which frees request slots as they arrive.
+func pollLoop() {
+	if err := enableSimulatorMode(currentPort()); err != nil {
+		uiLogger("Error sending DUML command: %v", err)
+	}
+
+	for {
+		select {
+		case <-stopChan:
+			uiLogger("Poll loop stopped")
+			return
+		default:
+		}
+
+		port := currentPort()
+		if port == nil {
+			uiLogger("Serial port is no longer available")
+			return
+		}
+
+		now := time.Now()
+		if silence, _, retry := stallDetector.Check(now); retry {
+			recoverStalledRC(silence)
+			continue
+		}
+
+		if !pollScheduler.Ready(now) {
+			wait := pollScheduler.Delay(now)
+			if wait == 0 {
+				// All request slots are taken, wait for a reply or give up on the oldest request
+				wait = min(pollReplyTimeout, stallDetector.Timeout())
+			}
+			select {
+			case <-stopChan:
+			case <-pollScheduler.Wake():
+			case <-time.After(wait):
+				if n := pollScheduler.Expire(time.Now(), pollReplyTimeout); n > 0 && !stallDetector.Stalled() {
+					uiLogger("%d channel request(s) unanswered, poll %v", n, pollScheduler)
+				}
+			}
+			continue
+		}
+
+		// Request latest channel values from RC
+		seq, err := sendDUML(port, 0x0a, 0x06, 0x40, 0x06, 0x01, nil)
+		if err != nil {
+			if !stallDetector.Stalled() {
+				uiLogger("Error requesting channel values: %v", err)
+			}
+			time.Sleep(100 * time.Millisecond)
+			continue
+		}
+		pollScheduler.Sent(seq, now)
+	}
+}
+
+// serialReadLoop reads replies from the RC and updates the stick positions
+func serialReadLoop() {
+	reader := helper.NewFrameReader(currentPort())
+	rawValues := make(map[string]uint16, len(helper.KnownChannels))
 
 	for {
 		select {
@@ -235,56 +558,113 @@ func serialReadLoop() {
 			uiLogger("Serial read loop stopped")
 			return
 		default:
-			if serialPort == nil {
+			port := currentPort()
+			if port == nil {
 				uiLogger("Serial port is no longer available")
 				return
 			}
-			// Request latest channel values from RC
-			if err := sendDUML(serialPort, 0x0a, 0x06, 0x40, 0x06, 0x01, nil); err != nil {
-				uiLogger("Error requesting channel values: %v", err)
-				time.Sleep(100 * time.Millisecond)
-				continue
+			if reader.Transport() != io.Reader(port) {
+				// The port was reopened after a stall, buffered bytes belong to the old one
+				reader.Reset(port)
 			}
 
-			// Read packet header
-			packetBuffer, packetLength, err := helper.ReadPacketHeader(serialPort, buffer)
+			// Read the next frame, it is only valid until the next call
+			packetBuffer, err := reader.Next()
 			if err != nil {
-				uiLogger("Error reading packet header: %v", err)
-				continue
-			}
-
-			// Read packet body
-			remainingBytes := int(packetLength) - 4
-			if remainingBytes > 0 {
-				n, err := helper.ReadBytes(serialPort, buffer, remainingBytes)
-				if err != nil || n != remainingBytes {
-					uiLogger("Error reading packet body: %v (expected: %d, actual: %d)", err, remainingBytes, n)
-					continue
+				// Silence is reported by the stall detector
+				if !errors.Is(err, helper.ErrReadTimeout) {
+					logReadError(err, "Error reading packet: %v", err)
 				}
-				packetBuffer = append(packetBuffer, buffer[:n]...)
+				continue
 			}
+			replyAt := time.Now()
 
 			// Parse stick positions from 38-byte controller input packets
 			if len(packetBuffer) == 38 {
-				// Map raw values to virtual controller ranges
-				rightHorizontal, rightVertical, leftVertical, leftHorizontal, cd, err := getStickStatus(packetBuffer)
-				if err != nil {
+				// Pair the reply with its request by sequence number
+				seq := binary.LittleEndian.Uint16(packetBuffer[6:8])
+				sent, _ := pollScheduler.Completed(seq, replyAt)
+				stamps := helper.FrameStamps{PollSent: sent, ReplyComplete: replyAt}
+
+				if err := getStickStatus(packetBuffer, rawValues); err != nil {
 					uiLogger("Error validating packet: %v", err)
 					continue
 				}
-				// Update stick positions
-				stickPositions["right_horizontal"] = rightHorizontal
-				stickPositions["right_vertical"] = rightVertical
-				stickPositions["left_vertical"] = leftVertical
-				stickPositions["left_horizontal"] = leftHorizontal
-				cameraDial = cd
-			}
+				stamps.Decoded = time.Now()
+				latency.RecordReply(stamps)
+				failsafe.Fresh(replyAt)
+				if silence := stallDetector.Reply(replyAt); silence > 0 {
+					uiLogger("RC replies resumed after %d ms", silence.Milliseconds())
+					updateStatus(runningStatus())
+				}
+
+				if wizard := currentWizard(); wizard != nil {
+					wizard.Feed(rawValues)
+				}
+
+				cal := currentCalibration()
+				auto := currentAutoCalibrator()
+				if auto != nil {
+					for _, drift := range auto.Observe(replyAt, rawValues) {
+						uiLogger("Warning: %v, consider recalibrating", drift)
+					}
+				}
 
-			time.Sleep(10 * time.Millisecond)
+				// Map raw values to virtual controller ranges, smooth them and update stick positions
+				mapper := currentMapper()
+				mode := currentStickMode()
+				stateMutex.Lock()
+				for name, raw := range rawValues {
+					cc := cal.Channel(name)
+					if auto != nil {
+						cc = auto.Channel(name)
+					}
+					stickPositions[name] = mapper.Filter(replyAt, mode.ProfileChannel(name), parseInput(raw, cc))
+					rawPositions[name] = raw
+				}
+				frameStamps = stamps
+				stateMutex.Unlock()
+				notifyFrame()
+			}
 		}
 	}
 }
 
+// notifyFrame wakes the gamepad loop without waiting for it
+func notifyFrame() {
+	select {
+	case frameReady <- struct{}{}:
+	default:
+	}
+}
+
+// logReadError logs a serial read error. Errors of the port itself (e.g. an unplugged RC)
+// also pause reading briefly while the poll loop reopens the port.
+func logReadError(err error, format string, args ...any) {
+	var portErr *serial.PortError
+	if !errors.As(err, &portErr) {
+		uiLogger(format, args...)
+		return
+	}
+	if !stallDetector.Stalled() {
+		uiLogger(format, args...)
+	}
+	time.Sleep(100 * time.Millisecond)
+}
+
+// logLatencySummary writes the current latency histograms to the log view
+func logLatencySummary() {
+	for _, line := range strings.Split(latency.Summary(), "\n") {
+		uiLogger("%s", line)
+	}
+	if pollScheduler != nil {
+		uiLogger("  poll:       %v", pollScheduler)
+	}
+	if mapper := currentMapper(); mapper != nil {
+		logFilterDelays(mapper.Profile(), "  ")
+	}
+}
+
 // cleanupAndExit performs cleanup before exiting
 func cleanupAndExit() {
 	uiLogger("Shutting down...")
@@ -297,14 +677,15 @@ func cleanupAndExit() {
 
 	// Wait briefly for goroutines to finish (optional, needs sync.WaitGroup for reliability)
 	time.Sleep(200 * time.Millisecond)
+	logLatencySummary()
+	saveLearnedCalibration()
 
 	// Close resources
-	if serialPort != nil {
+	if currentPort() != nil {
 		uiLogger("Closing serial port...")
-		if err := serialPort.Close(); err != nil {
+		if err := closeSerialPort(); err != nil {
 			uiLogger("Error closing serial port: %v", err)
 		}
-		serialPort = nil
 	}
 
 	if gamepad != nil {
@@ -341,6 +722,8 @@ func safeGoroutine(name string, fn func()) {
 }
 
 func main() {
+	flag.Parse()
+
 	// Create a log file to capture startup errors
 	logFile, err := os.OpenFile("startup_log.txt", os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
 	if err == nil {
@@ -368,6 +751,27 @@ func runApplication() error {
 		return fmt.Errorf("failed to create main window: %w", err)
 	}
 
+	exportDefaultProfile(*profilesDir)
+	if err := loadProfiles(*profilesDir); err != nil {
+		return fmt.Errorf("failed to load profiles: %w", err)
+	}
+	if err := selectProfile(*profileName); err != nil {
+		uiLogger("Error: %v, falling back to the %q profile", err, defaultProfileName)
+		if err := selectProfile(defaultProfileName); err != nil {
+			return err
+		}
+	}
+	if *ratesFile != "" {
+		data, err := os.ReadFile(*ratesFile)
+		if err == nil {
+			err = importRates(string(data))
+		}
+		if err != nil {
+			uiLogger("Error importing rates: %v", err)
+		}
+	}
+	syncProfileBox()
+
 	// Set initial status
 	updateStatus("Ready - Click Start")
 	uiLogger("Application initialized. Waiting for user action.")
@@ -394,6 +798,21 @@ func loadAppIcon() (*walk.Icon, error) {
 	return walk.NewIconFromFile(iconPath)
 }
 
+// syncProfileBox lists the loaded profiles in the profile selector and selects the active one
+func syncProfileBox() {
+	if profileBox == nil {
+		return
+	}
+	names := profileNames()
+	active := currentMapper().Profile().Name
+	profileBox.SetModel(names)
+	for i, name := range names {
+		if name == active {
+			profileBox.SetCurrentIndex(i)
+		}
+	}
+}
+
 // Wrap main window creation in a function with error handling
 func createMainWindow() error {
 	defer func() {
@@ -424,6 +843,26 @@ func createMainWindow() error {
 			}
 		},
 		Children: []Widget{
+			Composite{
+				Layout: HBox{MarginsZero: true},
+				Children: []Widget{
+					Label{Text: "Profile:"},
+					ComboBox{
+						AssignTo: &profileBox,
+						MaxSize:  Size{Width: 2000, Height: 0},
+						OnCurrentIndexChanged: func() {
+							names := profileNames()
+							idx := profileBox.CurrentIndex()
+							if idx < 0 || idx >= len(names) || names[idx] == currentMapper().Profile().Name {
+								return
+							}
+							if err := selectProfile(names[idx]); err != nil {
+								uiLogger("Error: %v", err)
+							}
+						},
+					},
+				},
+			},
 			VSplitter{
 				Children: []Widget{
 					Label{
@@ -476,20 +915,58 @@ func createMainWindow() error {
 							// Wait for goroutines to finish
 							time.Sleep(300 * time.Millisecond)
 
-							if serialPort != nil {
-								serialPort.Close()
-								serialPort = nil
-							}
+							closeSerialPort()
+							cancelCalibration()
+							saveLearnedCalibration()
 
 							if gamepad != nil {
 								gamepad.Close()
 								gamepad = nil
 							}
 
+							logLatencySummary()
 							updateStatus("Stopped")
 							uiLogger("Translator stopped.")
 						},
 					},
+					PushButton{
+						AssignTo: &calibButton,
+						Text:     "Calibrate",
+						MaxSize:  Size{Width: 2000, Height: 0},
+						OnClicked: func() {
+							advanceCalibration()
+						},
+					},
+					PushButton{
+						AssignTo: &curvesButton,
+						Text:     "Curves",
+						MaxSize:  Size{Width: 2000, Height: 0},
+						OnClicked: func() {
+							exportCurves()
+						},
+					},
+					PushButton{
+						AssignTo: &ratesButton,
+						Text:     "Rates",
+						MaxSize:  Size{Width: 2000, Height: 0},
+						OnClicked: func() {
+							text, err := walk.Clipboard().Text()
+							if err == nil {
+								err = importRates(text)
+							}
+							if err != nil {
+								uiLogger("Error importing rates from clipboard: %v", err)
+							}
+						},
+					},
+					PushButton{
+						AssignTo: &statsButton,
+						Text:     "Latency",
+						MaxSize:  Size{Width: 2000, Height: 0},
+						OnClicked: func() {
+							logLatencySummary()
+						},
+					},
 					PushButton{
 						AssignTo: &exitButton,
 						Text:     "Exit",
@@ -0,0 +1,149 @@
+package helper
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Auto-calibration tuning
+const (
+	autoCalInitialSpan = 495                    // raw units either side of center before any stick travel was seen (75% of nominal)
+	restTolerance      = 6                      // raw jitter tolerated while a stick rests
+	restDuration       = 750 * time.Millisecond // how long a stick must stay still to count as resting
+	restWindow         = 0.15                   // resting positions further from center than this fraction of the span are held deflections
+	centerWeight       = 0.02                   // EWMA weight of each resting sample in the center estimate
+)
+
+// CenterDrift reports a channel whose resting position moved away from its reference center
+type CenterDrift struct {
+	Channel   string
+	Center    uint16
+	Reference uint16
+}
+
+func (d CenterDrift) String() string {
+	return fmt.Sprintf("%s center drifted to %d (reference %d, %+d)", d.Channel, d.Center, d.Reference, int(d.Center)-int(d.Reference))
+}
+
+type restTracker struct {
+	anchor uint16
+	since  time.Time
+}
+
+// AutoCalibrator learns channel calibrations during normal use. It widens each channel's
+// range whenever a stick travels further than seen before and re-estimates the center while
+// a stick rests. It is safe for concurrent use.
+type AutoCalibrator struct {
+	mu             sync.Mutex
+	cal            Calibration
+	centers        map[string]float64
+	reference      map[string]uint16
+	rest           map[string]*restTracker
+	drifted        map[string]bool
+	driftThreshold int
+}
+
+// NewAutoCalibrator starts learning from base. Channels without a valid base calibration
+// start with a narrow range around the nominal center, which widens as the sticks move.
+// A center further than driftThreshold raw units from its starting value is reported as drift.
+func NewAutoCalibrator(base Calibration, channels []string, driftThreshold int) *AutoCalibrator {
+	a := &AutoCalibrator{
+		cal:            make(Calibration),
+		centers:        make(map[string]float64),
+		reference:      make(map[string]uint16),
+		rest:           make(map[string]*restTracker),
+		drifted:        make(map[string]bool),
+		driftThreshold: driftThreshold,
+	}
+	for _, name := range channels {
+		cc, ok := base[name]
+		if !ok || !cc.Valid() {
+			center := DefaultChannelCalibration.Center
+			cc = ChannelCalibration{Min: center - autoCalInitialSpan, Center: center, Max: center + autoCalInitialSpan}
+		}
+		a.cal[name] = cc
+		a.centers[name] = float64(cc.Center)
+		a.reference[name] = cc.Center
+		a.rest[name] = &restTracker{}
+	}
+	return a
+}
+
+// Observe learns from one set of raw channel values and returns channels whose center
+// just drifted beyond the threshold
+func (a *AutoCalibrator) Observe(now time.Time, raw map[string]uint16) (drifts []CenterDrift) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for name, v := range raw {
+		cc, ok := a.cal[name]
+		if !ok {
+			continue
+		}
+		cc.Min = min(cc.Min, v)
+		cc.Max = max(cc.Max, v)
+
+		rt := a.rest[name]
+		if absDiff(v, rt.anchor) > restTolerance {
+			rt.anchor, rt.since = v, now
+		} else if now.Sub(rt.since) >= restDuration {
+			span := float64(min(cc.Center-cc.Min, cc.Max-cc.Center))
+			if offset := float64(v) - a.centers[name]; offset <= span*restWindow && -offset <= span*restWindow {
+				a.centers[name] += offset * centerWeight
+				cc.Center = uint16(a.centers[name] + 0.5)
+			}
+		}
+		a.cal[name] = cc
+
+		drift := absDiff(cc.Center, a.reference[name])
+		if !a.drifted[name] && drift > a.driftThreshold {
+			a.drifted[name] = true
+			drifts = append(drifts, CenterDrift{Channel: name, Center: cc.Center, Reference: a.reference[name]})
+		} else if a.drifted[name] && drift <= a.driftThreshold/2 {
+			a.drifted[name] = false
+		}
+	}
+	return drifts
+}
+
+// Channel returns the learned calibration of a channel, falling back to the nominal range
+func (a *AutoCalibrator) Channel(name string) ChannelCalibration {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.cal.Channel(name)
+}
+
+// Calibration returns a copy of all learned calibrations
+func (a *AutoCalibrator) Calibration() Calibration {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	cal := make(Calibration, len(a.cal))
+	for name, cc := range a.cal {
+		cal[name] = cc
+	}
+	return cal
+}
+
+// Rebase restarts learning from a new calibration, e.g. after running the wizard
+func (a *AutoCalibrator) Rebase(base Calibration) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for name := range a.cal {
+		if cc, ok := base[name]; ok && cc.Valid() {
+			a.cal[name] = cc
+			a.centers[name] = float64(cc.Center)
+			a.reference[name] = cc.Center
+			a.drifted[name] = false
+		}
+	}
+}
+
+func absDiff(a, b uint16) int {
+	if a > b {
+		return int(a - b)
+	}
+	return int(b - a)
+}
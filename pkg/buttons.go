@@ -0,0 +1,276 @@
+package helper
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/CB2Moon/vgamepad-go/pkg/commons"
+)
+
+// Button rule modes
+const (
+	ButtonHold   = "hold"   // pressed while the channel is past the threshold
+	ButtonPulse  = "pulse"  // one short press each time the channel crosses the threshold
+	ButtonRepeat = "repeat" // a press on crossing, then repeated presses while held
+	ButtonToggle = "toggle" // each crossing flips the button between pressed and released
+	ButtonTap    = "tap"    // separate buttons for a short tap, a long hold and a double tap
+)
+
+// Button rule defaults
+const (
+	defaultHysteresis     = 2048
+	defaultPulse          = 100 * time.Millisecond
+	defaultRepeatDelay    = 500 * time.Millisecond
+	defaultRepeatInterval = 150 * time.Millisecond
+	defaultHoldTime       = 500 * time.Millisecond
+	defaultDoubleTapTime  = 300 * time.Millisecond
+)
+
+// minRepeatIntervalMs keeps repeated presses and the releases between them at least 20 ms
+// long, so sims sampling the gamepad once per rendered frame see each one
+const minRepeatIntervalMs = 40
+
+// ButtonRule presses a button while a channel is past a threshold. Enter and exit thresholds
+// differ so a channel resting near the threshold does not chatter the button.
+type ButtonRule struct {
+	Channel string `json:"channel"`
+	Button  string `json:"button"`
+	// Enter activates the rule: positive values at or above it, negative values at or below it
+	Enter int16 `json:"enter"`
+	// Exit releases the rule again once the channel falls back past it towards center
+	// (default 2048 closer to center than Enter)
+	Exit *int16 `json:"exit,omitempty"`
+	// Mode is hold (default), pulse, repeat, toggle or tap
+	Mode string `json:"mode,omitempty"`
+	// PulseMs is how long pulse and repeat presses last (default 100)
+	PulseMs int `json:"pulse_ms,omitempty"`
+	// RepeatDelayMs is how long the channel has to stay active before repeating starts (default 500)
+	RepeatDelayMs int `json:"repeat_delay_ms,omitempty"`
+	// RepeatIntervalMs is the time between repeated presses (default 150)
+	RepeatIntervalMs int `json:"repeat_interval_ms,omitempty"`
+	// In tap mode Button is pulsed on a short tap, Hold is pressed once the channel stays
+	// active for HoldMs (default 500) and DoubleTap is pulsed on a second tap within
+	// DoubleTapMs (default 300). Hold and DoubleTap are optional; without DoubleTap taps are
+	// reported immediately instead of after the double tap window.
+	Hold        string `json:"hold,omitempty"`
+	DoubleTap   string `json:"double_tap,omitempty"`
+	HoldMs      int    `json:"hold_ms,omitempty"`
+	DoubleTapMs int    `json:"double_tap_ms,omitempty"`
+}
+
+// Validate checks the rule's channel, button, thresholds and timings
+func (r *ButtonRule) Validate() error {
+	if err := validateChannel(r.Channel); err != nil {
+		return err
+	}
+	if _, err := ParseButton(r.Button); err != nil {
+		return err
+	}
+	switch strings.ToLower(r.Mode) {
+	case "", ButtonHold, ButtonPulse, ButtonRepeat, ButtonToggle, ButtonTap:
+	default:
+		return fmt.Errorf("unknown mode %q (want hold, pulse, repeat, toggle or tap)", r.Mode)
+	}
+	for _, name := range []string{r.Hold, r.DoubleTap} {
+		if name == "" {
+			continue
+		}
+		if !strings.EqualFold(r.Mode, ButtonTap) {
+			return fmt.Errorf("hold and double_tap need tap mode")
+		}
+		if _, err := ParseButton(name); err != nil {
+			return err
+		}
+	}
+	if r.Enter == 0 {
+		return fmt.Errorf("enter threshold must not be 0")
+	}
+	if r.Exit != nil && (r.Enter > 0 && (*r.Exit > r.Enter || *r.Exit < 0) || r.Enter < 0 && (*r.Exit < r.Enter || *r.Exit > 0)) {
+		return fmt.Errorf("exit threshold %d must lie between center and enter threshold %d", *r.Exit, r.Enter)
+	}
+	if r.PulseMs < 0 || r.RepeatDelayMs < 0 || r.RepeatIntervalMs < 0 || r.HoldMs < 0 || r.DoubleTapMs < 0 {
+		return fmt.Errorf("timings must not be negative")
+	}
+	if r.RepeatIntervalMs > 0 && r.RepeatIntervalMs < minRepeatIntervalMs {
+		return fmt.Errorf("repeat_interval_ms must be at least %d", minRepeatIntervalMs)
+	}
+	return nil
+}
+
+// buttonRule is a compiled ButtonRule with its runtime state
+type buttonRule struct {
+	channel  string
+	button   commons.XUSBButton
+	enter    int16
+	exit     int16
+	mode     string
+	pulse    time.Duration
+	delay    time.Duration
+	interval time.Duration
+
+	// Tap mode
+	hold      commons.XUSBButton
+	doubleTap commons.XUSBButton
+	holdTime  time.Duration
+	tapWindow time.Duration
+
+	active     bool
+	waitCenter bool // after reset, ignore the channel until it is back inside exit
+	latched    bool
+	pressed    bool
+	pulseUntil time.Time
+	nextRepeat time.Time
+
+	since   time.Time          // tap mode: when the channel became active or was last released
+	holding bool               // tap mode: active longer than holdTime
+	taps    int                // tap mode: taps waiting for the double tap window to close
+	pulsed  commons.XUSBButton // tap mode: button of the running pulse
+}
+
+// newButtonRule compiles a validated rule, filling in defaults
+func newButtonRule(r ButtonRule) *buttonRule {
+	b, _ := ParseButton(r.Button)
+	c := &buttonRule{
+		channel:   r.Channel,
+		button:    b,
+		enter:     r.Enter,
+		mode:      strings.ToLower(r.Mode),
+		pulse:     msOrDefault(r.PulseMs, defaultPulse),
+		delay:     msOrDefault(r.RepeatDelayMs, defaultRepeatDelay),
+		interval:  msOrDefault(r.RepeatIntervalMs, defaultRepeatInterval),
+		holdTime:  msOrDefault(r.HoldMs, defaultHoldTime),
+		tapWindow: msOrDefault(r.DoubleTapMs, defaultDoubleTapTime),
+	}
+	if r.Hold != "" {
+		c.hold, _ = ParseButton(r.Hold)
+	}
+	if r.DoubleTap != "" {
+		c.doubleTap, _ = ParseButton(r.DoubleTap)
+	}
+	if c.mode == "" {
+		c.mode = ButtonHold
+	}
+	switch {
+	case r.Exit != nil:
+		c.exit = *r.Exit
+	case r.Enter > 0:
+		c.exit = ClampAxis(int32(r.Enter) - defaultHysteresis)
+	default:
+		c.exit = ClampAxis(int32(r.Enter) + defaultHysteresis)
+	}
+	return c
+}
+
+// update feeds the rule the channel's current value and returns the buttons it presses
+func (r *buttonRule) update(now time.Time, v int16) commons.XUSBButton {
+	if r.waitCenter {
+		if r.enter > 0 && v > r.exit || r.enter < 0 && v < r.exit {
+			return 0
+		}
+		r.waitCenter = false
+	}
+
+	wasActive := r.active
+	if r.enter > 0 {
+		r.active = v >= r.enter || r.active && v > r.exit
+	} else {
+		r.active = v <= r.enter || r.active && v < r.exit
+	}
+
+	if r.mode == ButtonTap {
+		return r.updateTap(now, wasActive)
+	}
+
+	if r.active && !wasActive {
+		switch r.mode {
+		case ButtonPulse:
+			r.pulseUntil = now.Add(r.pulse)
+		case ButtonRepeat:
+			// Presses last at most half the gap to the next one, so each is released before
+			// the next starts
+			r.pulseUntil = now.Add(min(r.pulse, r.delay/2))
+			r.nextRepeat = now.Add(r.delay)
+		case ButtonToggle:
+			r.latched = !r.latched
+		}
+	} else if r.active && r.mode == ButtonRepeat && !now.Before(r.nextRepeat) {
+		r.pulseUntil = now.Add(min(r.pulse, r.interval/2))
+		r.nextRepeat = r.nextRepeat.Add(r.interval)
+		if r.nextRepeat.Before(now) {
+			r.nextRepeat = now.Add(r.interval)
+		}
+	}
+
+	switch r.mode {
+	case ButtonToggle:
+		r.pressed = r.latched
+	case ButtonPulse, ButtonRepeat:
+		r.pressed = now.Before(r.pulseUntil)
+	default:
+		r.pressed = r.active
+	}
+	if r.pressed {
+		return r.button
+	}
+	return 0
+}
+
+// updateTap tells taps, holds and double taps apart once the active state was updated
+func (r *buttonRule) updateTap(now time.Time, wasActive bool) commons.XUSBButton {
+	switch {
+	case r.active && !wasActive:
+		r.since = now
+	case r.active:
+		if r.hold != 0 && !r.holding && now.Sub(r.since) >= r.holdTime {
+			// A hold cancels a pending tap
+			r.holding, r.taps = true, 0
+		}
+	case wasActive:
+		if r.holding {
+			r.holding = false
+			break
+		}
+		r.taps++
+		r.since = now
+		if r.doubleTap == 0 {
+			r.startPulse(now, r.button)
+		} else if r.taps == 2 {
+			r.startPulse(now, r.doubleTap)
+		}
+	case r.taps == 1 && now.Sub(r.since) > r.tapWindow:
+		// No second tap came
+		r.startPulse(now, r.button)
+	}
+
+	var buttons commons.XUSBButton
+	if r.holding {
+		buttons |= r.hold
+	}
+	if now.Before(r.pulseUntil) {
+		buttons |= r.pulsed
+	}
+	r.pressed = buttons != 0
+	return buttons
+}
+
+// startPulse pulses a tap mode button and clears pending taps
+func (r *buttonRule) startPulse(now time.Time, b commons.XUSBButton) {
+	r.pulsed, r.pulseUntil, r.taps = b, now.Add(r.pulse), 0
+}
+
+// reset releases the rule. A channel that is still past the threshold has to return towards
+// center before the rule activates again, so switching mappings does not fire presses.
+func (r *buttonRule) reset() {
+	r.active, r.latched, r.pressed = false, false, false
+	r.holding, r.taps = false, 0
+	r.waitCenter = true
+	r.pulseUntil = time.Time{}
+}
+
+func msOrDefault(ms int, def time.Duration) time.Duration {
+	if ms == 0 {
+		return def
+	}
+	return time.Duration(ms) * time.Millisecond
+}
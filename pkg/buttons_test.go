@@ -0,0 +1,217 @@
+package helper
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/CB2Moon/vgamepad-go/pkg/commons"
+)
+
+// frameInterval is the spacing of RC frames the rules are fed at in these tests
+const frameInterval = 10 * time.Millisecond
+
+// buttonTimeline feeds a rule one value per frame and returns the frame times, in ms from the
+// start, at which each button went down
+func buttonTimeline(r *buttonRule, values func(ms int) int16, duration time.Duration) map[commons.XUSBButton][]int {
+	start := time.Unix(1000, 0)
+	presses := make(map[commons.XUSBButton][]int)
+	var prev commons.XUSBButton
+	for t := time.Duration(0); t <= duration; t += frameInterval {
+		ms := int(t / time.Millisecond)
+		buttons := r.update(start.Add(t), values(ms))
+		for _, b := range AllButtons {
+			if buttons&b != 0 && prev&b == 0 {
+				presses[b] = append(presses[b], ms)
+			}
+		}
+		prev = buttons
+	}
+	return presses
+}
+
+// heldFor is a channel pushed to full deflection between from and to ms
+func heldFor(from, to int) func(int) int16 {
+	return func(ms int) int16 {
+		if ms >= from && ms < to {
+			return 32767
+		}
+		return 0
+	}
+}
+
+func ptr16(v int16) *int16 { return &v }
+
+func TestButtonRuleHysteresis(t *testing.T) {
+	r := newButtonRule(ButtonRule{Channel: "camera_dial", Button: "a", Enter: 20000, Exit: ptr16(15000)})
+	now := time.Unix(1000, 0)
+	steps := []struct {
+		v       int16
+		pressed bool
+	}{
+		{19999, false},
+		{20000, true},
+		{16000, true}, // between exit and enter stays pressed
+		{15000, false},
+		{19000, false}, // below enter stays released
+		{-32768, false},
+	}
+	for i, s := range steps {
+		if got := r.update(now, s.v) != 0; got != s.pressed {
+			t.Errorf("step %d: value %d pressed = %v, want %v", i, s.v, got, s.pressed)
+		}
+	}
+
+	neg := newButtonRule(ButtonRule{Channel: "camera_dial", Button: "b", Enter: -20000})
+	for i, s := range []struct {
+		v       int16
+		pressed bool
+	}{{-20000, true}, {-18000, true}, {-17952, false}, {20000, false}} {
+		if got := neg.update(now, s.v) != 0; got != s.pressed {
+			t.Errorf("negative step %d: value %d pressed = %v, want %v", i, s.v, got, s.pressed)
+		}
+	}
+}
+
+func TestButtonRuleValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		rule ButtonRule
+		ok   bool
+	}{
+		{"enter only", ButtonRule{Enter: 16000}, true},
+		{"exit inside", ButtonRule{Enter: 16000, Exit: ptr16(12000)}, true},
+		{"exit at center", ButtonRule{Enter: 16000, Exit: ptr16(0)}, true},
+		{"exit at enter", ButtonRule{Enter: -16000, Exit: ptr16(-16000)}, true},
+		{"negative exit inside", ButtonRule{Enter: -16000, Exit: ptr16(-12000)}, true},
+		{"zero enter", ButtonRule{}, false},
+		{"exit beyond enter", ButtonRule{Enter: 16000, Exit: ptr16(20000)}, false},
+		{"negative exit beyond enter", ButtonRule{Enter: -16000, Exit: ptr16(-20000)}, false},
+		{"exit past center", ButtonRule{Enter: 16000, Exit: ptr16(-5000)}, false},
+		{"negative exit past center", ButtonRule{Enter: -16000, Exit: ptr16(5000)}, false},
+		{"unknown mode", ButtonRule{Enter: 16000, Mode: "latch"}, false},
+		{"hold without tap mode", ButtonRule{Enter: 16000, Hold: "b"}, false},
+	}
+	for _, tt := range tests {
+		tt.rule.Channel, tt.rule.Button = "camera_dial", "a"
+		if err := tt.rule.Validate(); (err == nil) != tt.ok {
+			t.Errorf("%s: error %v, want ok %v", tt.name, err, tt.ok)
+		}
+	}
+}
+
+func TestButtonRuleModes(t *testing.T) {
+	a := commons.XUSB_GAMEPAD_A
+	tests := []struct {
+		name     string
+		rule     ButtonRule
+		values   func(int) int16
+		duration time.Duration
+		want     []int
+	}{
+		{"hold", ButtonRule{Mode: ButtonHold}, heldFor(50, 300), 500 * time.Millisecond, []int{50}},
+		{"pulse once per crossing", ButtonRule{Mode: ButtonPulse}, heldFor(50, 1000), 1200 * time.Millisecond, []int{50}},
+		{"repeat", ButtonRule{Mode: ButtonRepeat, RepeatDelayMs: 500, RepeatIntervalMs: 150},
+			heldFor(0, 1000), 1000 * time.Millisecond, []int{0, 500, 650, 800, 950}},
+		{"fast repeat", ButtonRule{Mode: ButtonRepeat, RepeatDelayMs: 200, RepeatIntervalMs: 40},
+			heldFor(0, 330), 400 * time.Millisecond, []int{0, 200, 240, 280, 320}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.rule.Channel, tt.rule.Button, tt.rule.Enter = "camera_dial", "a", 16384
+			got := buttonTimeline(newButtonRule(tt.rule), tt.values, tt.duration)[a]
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("presses at %v ms, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestButtonRulePulseLength(t *testing.T) {
+	r := newButtonRule(ButtonRule{Channel: "camera_dial", Button: "a", Enter: 16384, Mode: ButtonPulse, PulseMs: 60})
+	start := time.Unix(1000, 0)
+	var pressedMs int
+	for ms := 0; ms < 300; ms += 10 {
+		if r.update(start.Add(time.Duration(ms)*time.Millisecond), 32767) != 0 {
+			pressedMs += 10
+		}
+	}
+	if pressedMs != 60 {
+		t.Errorf("pulse lasted %d ms, want 60", pressedMs)
+	}
+}
+
+func TestButtonRuleToggle(t *testing.T) {
+	r := newButtonRule(ButtonRule{Channel: "camera_dial", Button: "a", Enter: 16384, Mode: ButtonToggle})
+	now := time.Unix(1000, 0)
+	want := []bool{true, true, true, false, false, true}
+	for i, v := range []int16{32767, 32767, 0, 32767, 0, 32767} {
+		if got := r.update(now, v) != 0; got != want[i] {
+			t.Errorf("step %d: pressed = %v, want %v", i, got, want[i])
+		}
+	}
+}
+
+func TestButtonRuleTap(t *testing.T) {
+	rule := ButtonRule{Channel: "camera_dial", Button: "a", Hold: "b", DoubleTap: "x", Enter: 16384, Mode: ButtonTap}
+	a, b, x := commons.XUSB_GAMEPAD_A, commons.XUSB_GAMEPAD_B, commons.XUSB_GAMEPAD_X
+	tests := []struct {
+		name   string
+		values func(int) int16
+		want   map[commons.XUSBButton][]int
+	}{
+		// A single tap is reported once the 300 ms double tap window after its release closes
+		{"tap", heldFor(0, 100), map[commons.XUSBButton][]int{a: {410}}},
+		{"hold", heldFor(0, 800), map[commons.XUSBButton][]int{b: {500}}},
+		{"double tap", func(ms int) int16 {
+			return heldFor(0, 100)(ms) + heldFor(200, 300)(ms)
+		}, map[commons.XUSBButton][]int{x: {300}}},
+		{"taps too far apart", func(ms int) int16 {
+			return heldFor(0, 100)(ms) + heldFor(500, 600)(ms)
+		}, map[commons.XUSBButton][]int{a: {410, 910}}},
+		// Taps shorter than the old 100 ms update tick, one and two frames long
+		{"quick tap", heldFor(120, 130), map[commons.XUSBButton][]int{a: {440}}},
+		{"quick double tap", func(ms int) int16 {
+			return heldFor(120, 140)(ms) + heldFor(180, 200)(ms)
+		}, map[commons.XUSBButton][]int{x: {200}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := buttonTimeline(newButtonRule(rule), tt.values, 1200*time.Millisecond)
+			for _, btn := range []commons.XUSBButton{a, b, x} {
+				if !slices.Equal(got[btn], tt.want[btn]) {
+					t.Errorf("button %v pressed at %v ms, want %v", btn, got[btn], tt.want[btn])
+				}
+			}
+		})
+	}
+}
+
+func TestButtonRuleResetWaitsForCenter(t *testing.T) {
+	r := newButtonRule(ButtonRule{Channel: "camera_dial", Button: "a", Enter: 16384})
+	now := time.Unix(1000, 0)
+	r.update(now, 32767)
+	r.reset()
+	if r.update(now, 32767) != 0 {
+		t.Fatal("rule pressed again before the channel returned to center")
+	}
+	r.update(now, 0)
+	if r.update(now, 32767) == 0 {
+		t.Fatal("rule not pressed after returning to center")
+	}
+}
+
+func TestRepeatIntervalMinimum(t *testing.T) {
+	rule := ButtonRule{Channel: "camera_dial", Button: "a", Enter: 16384, Mode: ButtonRepeat, RepeatIntervalMs: 30}
+	if err := rule.Validate(); err == nil {
+		t.Error("30 ms repeat interval accepted")
+	}
+	dpad := DpadConfig{Vertical: "camera_dial", RepeatIntervalMs: 120}
+	if err := dpad.Validate(); err != nil {
+		t.Errorf("120 ms D-pad repeat rejected: %v", err)
+	}
+	got := buttonTimeline(newButtonRule(dpad.Rules()[0]), heldFor(0, 800), 800*time.Millisecond)[commons.XUSB_GAMEPAD_DPAD_UP]
+	if want := []int{0, 500, 620, 740}; !slices.Equal(got, want) {
+		t.Errorf("D-pad presses at %v ms, want %v", got, want)
+	}
+}
@@ -0,0 +1,265 @@
+package helper
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ChannelCalibration holds the raw RC values of one channel at its extremes and at rest
+type ChannelCalibration struct {
+	Min    uint16 `json:"min"`
+	Center uint16 `json:"center"`
+	Max    uint16 `json:"max"`
+}
+
+// DefaultChannelCalibration is the nominal RC-Nx range (364 to 1024 to 1684)
+var DefaultChannelCalibration = ChannelCalibration{Min: 364, Center: 1024, Max: 1684}
+
+// minCalibrationSpan is the smallest distance between center and either extreme accepted as calibrated
+const minCalibrationSpan = 100
+
+// Valid reports whether the calibration spans a usable range on both sides of center
+func (c ChannelCalibration) Valid() bool {
+	return int(c.Center)-int(c.Min) >= minCalibrationSpan && int(c.Max)-int(c.Center) >= minCalibrationSpan
+}
+
+// Normalize converts a raw RC value to the Xbox controller range (-32768 to 0 to 32767).
+// Each side of center is scaled separately, so an off-center stick still reaches both ends.
+func (c ChannelCalibration) Normalize(raw uint16) int16 {
+	offset := int32(raw) - int32(c.Center)
+	var mapped int32
+	if offset >= 0 {
+		mapped = offset * 32767 / max(1, int32(c.Max)-int32(c.Center))
+	} else {
+		mapped = offset * 32768 / max(1, int32(c.Center)-int32(c.Min))
+	}
+	return ClampAxis(mapped)
+}
+
+func (c ChannelCalibration) String() string {
+	return fmt.Sprintf("%d/%d/%d", c.Min, c.Center, c.Max)
+}
+
+// Calibration maps channel names to their calibration
+type Calibration map[string]ChannelCalibration
+
+// Channel returns the calibration of a channel, falling back to the nominal range
+func (c Calibration) Channel(name string) ChannelCalibration {
+	if cc, ok := c[name]; ok && cc.Valid() {
+		return cc
+	}
+	return DefaultChannelCalibration
+}
+
+// String lists the calibration of all channels in a stable order
+func (c Calibration) String() string {
+	names := make([]string, 0, len(c))
+	for name := range c {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = fmt.Sprintf("%s=%v", name, c[name])
+	}
+	return strings.Join(parts, " ")
+}
+
+// CalibrationStore persists calibrations per RC, keyed by the RC's identity
+type CalibrationStore struct {
+	Devices map[string]Calibration `json:"devices"`
+}
+
+// LoadCalibrationStore reads a store from disk; a missing file yields an empty store
+func LoadCalibrationStore(path string) (*CalibrationStore, error) {
+	store := &CalibrationStore{Devices: make(map[string]Calibration)}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, store); err != nil {
+		return nil, fmt.Errorf("invalid calibration file: %w", err)
+	}
+	if store.Devices == nil {
+		store.Devices = make(map[string]Calibration)
+	}
+	return store, nil
+}
+
+// Save writes the store as indented JSON
+func (s *CalibrationStore) Save(path string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// Get returns the calibration stored for a device, nil if there is none
+func (s *CalibrationStore) Get(device string) Calibration {
+	return s.Devices[device]
+}
+
+// Set stores the calibration of a device
+func (s *CalibrationStore) Set(device string, cal Calibration) {
+	s.Devices[device] = cal
+}
+
+// CalibrationStep is the stage a calibration wizard is in
+type CalibrationStep int
+
+const (
+	CalibrationExtremes CalibrationStep = iota // sticks and dial are moved to all their extremes
+	CalibrationCenter                          // sticks and dial are released to rest
+	CalibrationDone
+)
+
+// centerSamples is how many of the latest samples are averaged into the center
+const centerSamples = 32
+
+type centerWindow struct {
+	samples [centerSamples]uint16
+	count   int
+}
+
+func (w *centerWindow) add(v uint16) {
+	w.samples[w.count%centerSamples] = v
+	w.count++
+}
+
+func (w *centerWindow) mean() uint16 {
+	n := min(w.count, centerSamples)
+	sum := 0
+	for _, v := range w.samples[:n] {
+		sum += int(v)
+	}
+	return uint16(sum / n)
+}
+
+// CalibrationWizard records the extremes and rest position of every channel from raw RC values.
+// It is safe for concurrent use.
+type CalibrationWizard struct {
+	mu      sync.Mutex
+	step    CalibrationStep
+	base    Calibration
+	min     map[string]uint16
+	max     map[string]uint16
+	centers map[string]*centerWindow
+}
+
+// NewCalibrationWizard starts a calibration; channels that are not moved keep their base calibration
+func NewCalibrationWizard(base Calibration) *CalibrationWizard {
+	return &CalibrationWizard{
+		step:    CalibrationExtremes,
+		base:    base,
+		min:     make(map[string]uint16),
+		max:     make(map[string]uint16),
+		centers: make(map[string]*centerWindow),
+	}
+}
+
+// Step returns the current stage
+func (w *CalibrationWizard) Step() CalibrationStep {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.step
+}
+
+// Feed records one set of raw channel values
+func (w *CalibrationWizard) Feed(raw map[string]uint16) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	switch w.step {
+	case CalibrationExtremes:
+		for name, v := range raw {
+			if lo, ok := w.min[name]; !ok || v < lo {
+				w.min[name] = v
+			}
+			if hi, ok := w.max[name]; !ok || v > hi {
+				w.max[name] = v
+			}
+		}
+	case CalibrationCenter:
+		for name, v := range raw {
+			cw, ok := w.centers[name]
+			if !ok {
+				cw = &centerWindow{}
+				w.centers[name] = cw
+			}
+			cw.add(v)
+		}
+	}
+}
+
+// Ranges describes the extremes recorded so far
+func (w *CalibrationWizard) Ranges() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	names := make([]string, 0, len(w.min))
+	for name := range w.min {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = fmt.Sprintf("%s=%d..%d", name, w.min[name], w.max[name])
+	}
+	return strings.Join(parts, " ")
+}
+
+// Next moves from recording extremes to recording the rest position
+func (w *CalibrationWizard) Next() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.step != CalibrationExtremes {
+		return fmt.Errorf("calibration is not recording extremes")
+	}
+	if len(w.min) == 0 {
+		return fmt.Errorf("no RC data received yet")
+	}
+	w.step = CalibrationCenter
+	return nil
+}
+
+// Finish completes the calibration. Channels whose recorded range is too small keep their
+// base calibration and are listed in skipped.
+func (w *CalibrationWizard) Finish() (cal Calibration, skipped []string, err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.step != CalibrationCenter {
+		return nil, nil, fmt.Errorf("calibration is not recording the rest position")
+	}
+	if len(w.centers) == 0 {
+		return nil, nil, fmt.Errorf("no RC data received while sticks were released")
+	}
+
+	cal = make(Calibration)
+	for name, cc := range w.base {
+		cal[name] = cc
+	}
+	for name, cw := range w.centers {
+		cc := ChannelCalibration{Min: w.min[name], Center: cw.mean(), Max: w.max[name]}
+		if !cc.Valid() {
+			skipped = append(skipped, name)
+			continue
+		}
+		cal[name] = cc
+	}
+	sort.Strings(skipped)
+	w.step = CalibrationDone
+	return cal, skipped, nil
+}
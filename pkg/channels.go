@@ -0,0 +1,22 @@
+package helper
+
+import "encoding/binary"
+
+// KnownChannels lists the RC channels the translator decodes, in packet order
+var KnownChannels = []string{"right_horizontal", "right_vertical", "left_vertical", "left_horizontal", "camera_dial"}
+
+// ChannelOffsets gives the position of each channel's little endian value in a 38-byte channel reply
+var ChannelOffsets = map[string]int{
+	"right_horizontal": 13,
+	"right_vertical":   16,
+	"left_vertical":    19,
+	"left_horizontal":  22,
+	"camera_dial":      25,
+}
+
+// DecodeRawChannels extracts the raw channel values of a validated 38-byte channel reply into raw
+func DecodeRawChannels(packet []byte, raw map[string]uint16) {
+	for name, offset := range ChannelOffsets {
+		raw[name] = binary.LittleEndian.Uint16(packet[offset : offset+2])
+	}
+}
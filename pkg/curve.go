@@ -0,0 +1,192 @@
+package helper
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+)
+
+// CurveConfig shapes the response of one channel. All values work on the normalized
+// stick range -1 to 1 and are applied in field order.
+type CurveConfig struct {
+	// Deadzone is the fraction around center that outputs zero (0 to 0.5)
+	Deadzone float64 `json:"deadzone,omitempty"`
+	// OuterDeadzone is the fraction at each end that already outputs full deflection (0 to 0.5)
+	OuterDeadzone float64 `json:"outer_deadzone,omitempty"`
+	// Expo softens the center while keeping full deflection (0 = linear to 1 = cubic)
+	Expo float64 `json:"expo,omitempty"`
+	// SuperRate steepens the ends and flattens the center further (0 to 0.95)
+	SuperRate float64 `json:"super_rate,omitempty"`
+	// Rate scales the whole output (default 1)
+	Rate float64 `json:"rate,omitempty"`
+	// Points is an optional piecewise-linear curve of [input, output] pairs applied last
+	Points [][2]float64 `json:"points,omitempty"`
+	// Rates replaces expo and super rate with a Betaflight, Actual or KISS rate model
+	Rates *RatesConfig `json:"rates,omitempty"`
+	// Throttle replaces expo and super rate with Betaflight's throttle curve
+	Throttle *ThrottleConfig `json:"throttle,omitempty"`
+}
+
+// Validate checks the curve parameters
+func (c *CurveConfig) Validate() error {
+	switch {
+	case c.Deadzone < 0 || c.Deadzone >= 0.5:
+		return fmt.Errorf("deadzone %v out of range 0 to 0.5", c.Deadzone)
+	case c.OuterDeadzone < 0 || c.OuterDeadzone >= 0.5:
+		return fmt.Errorf("outer_deadzone %v out of range 0 to 0.5", c.OuterDeadzone)
+	case c.Expo < 0 || c.Expo > 1:
+		return fmt.Errorf("expo %v out of range 0 to 1", c.Expo)
+	case c.SuperRate < 0 || c.SuperRate > 0.95:
+		return fmt.Errorf("super_rate %v out of range 0 to 0.95", c.SuperRate)
+	case c.Rate < 0 || c.Rate > 2:
+		return fmt.Errorf("rate %v out of range 0 to 2", c.Rate)
+	case len(c.Points) == 1:
+		return fmt.Errorf("points need at least two entries")
+	case c.Rates != nil && c.Throttle != nil:
+		return fmt.Errorf("rates and throttle cannot be combined")
+	}
+	if c.Rates != nil {
+		if err := c.Rates.Validate(); err != nil {
+			return err
+		}
+	}
+	if c.Throttle != nil {
+		if err := c.Throttle.Validate(); err != nil {
+			return err
+		}
+	}
+	for i := 1; i < len(c.Points); i++ {
+		if c.Points[i][0] <= c.Points[i-1][0] {
+			return fmt.Errorf("points must have strictly increasing inputs")
+		}
+	}
+	return nil
+}
+
+// Curve is a compiled response curve
+type Curve struct {
+	cfg CurveConfig
+}
+
+// NewCurve compiles a curve configuration
+func NewCurve(cfg CurveConfig) (*Curve, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	if cfg.Rate == 0 {
+		cfg.Rate = 1
+	}
+	return &Curve{cfg: cfg}, nil
+}
+
+// Eval computes the curve output for a normalized input (-1 to 1)
+func (c *Curve) Eval(x float64) float64 {
+	x = math.Max(-1, math.Min(1, x))
+	sign := 1.0
+	if x < 0 {
+		sign = -1
+	}
+	a := math.Abs(x)
+
+	// Deadzones, rescaling what is left to the full range
+	live := 1 - c.cfg.Deadzone - c.cfg.OuterDeadzone
+	a = math.Max(0, math.Min(1, (a-c.cfg.Deadzone)/live))
+
+	var y float64
+	switch {
+	case c.cfg.Throttle != nil:
+		y = c.cfg.Throttle.Eval(sign * a)
+	case c.cfg.Rates != nil:
+		y = c.cfg.Rates.Eval(sign * a)
+	default:
+		// Expo blends in a cubic term
+		a = (1-c.cfg.Expo)*a + c.cfg.Expo*a*a*a
+
+		// Super rate, normalized so full deflection stays full deflection
+		if sr := c.cfg.SuperRate; sr > 0 {
+			a = a * (1 - sr) / (1 - a*sr)
+		}
+		y = sign * a
+	}
+
+	y *= c.cfg.Rate
+	if len(c.cfg.Points) > 0 {
+		y = interpolate(c.cfg.Points, y)
+	}
+	return math.Max(-1, math.Min(1, y))
+}
+
+// Apply runs an Xbox range axis value through the curve
+func (c *Curve) Apply(v int16) int16 {
+	return FloatToAxis(c.Eval(AxisToFloat(v)))
+}
+
+// interpolate evaluates a piecewise-linear curve, holding the end values outside its range
+func interpolate(points [][2]float64, x float64) float64 {
+	i := sort.Search(len(points), func(i int) bool { return points[i][0] >= x })
+	switch i {
+	case 0:
+		return points[0][1]
+	case len(points):
+		return points[len(points)-1][1]
+	}
+	p0, p1 := points[i-1], points[i]
+	t := (x - p0[0]) / (p1[0] - p0[0])
+	return p0[1] + t*(p1[1]-p0[1])
+}
+
+// AxisToFloat converts an Xbox range axis value to -1 to 1
+func AxisToFloat(v int16) float64 {
+	if v < 0 {
+		return float64(v) / 32768
+	}
+	return float64(v) / 32767
+}
+
+// FloatToAxis converts -1 to 1 to the Xbox axis range
+func FloatToAxis(f float64) int16 {
+	if f < 0 {
+		return ClampAxis(int32(math.Round(f * 32768)))
+	}
+	return ClampAxis(int32(math.Round(f * 32767)))
+}
+
+// WriteCurvesCSV writes the response of every curve sampled at steps+1 inputs from -1 to 1,
+// one column per channel
+func WriteCurvesCSV(w io.Writer, curves map[string]*Curve, steps int) error {
+	names := make([]string, 0, len(curves))
+	for name := range curves {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write(append([]string{"input"}, names...)); err != nil {
+		return err
+	}
+	for i := 0; i <= steps; i++ {
+		x := -1 + 2*float64(i)/float64(steps)
+		row := []string{strconv.FormatFloat(x, 'f', 4, 64)}
+		for _, name := range names {
+			row = append(row, strconv.FormatFloat(curves[name].Eval(x), 'f', 4, 64))
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// DescribeCurve summarizes a curve by its output at 0, 25, 50, 75 and 100% stick
+func DescribeCurve(c *Curve) string {
+	desc := fmt.Sprintf("0%%->%.0f%% 25%%->%.0f%% 50%%->%.0f%% 75%%->%.0f%% 100%%->%.0f%%",
+		c.Eval(0)*100, c.Eval(0.25)*100, c.Eval(0.5)*100, c.Eval(0.75)*100, c.Eval(1)*100)
+	if r := c.cfg.Rates; r != nil {
+		desc += fmt.Sprintf(" (%s rates, %.0f deg/s at full stick)", r.Model, r.DegreesPerSecond(1))
+	}
+	return desc
+}
@@ -0,0 +1,84 @@
+package helper
+
+import "fmt"
+
+// defaultDpadThreshold is the deflection at which a D-pad channel starts pressing
+const defaultDpadThreshold = 16384
+
+// DpadConfig turns channels into D-pad presses with auto-repeat, for navigating sim menus
+type DpadConfig struct {
+	// Vertical presses up above the threshold and down below its negative
+	Vertical string `json:"vertical,omitempty"`
+	// Horizontal presses right above the threshold and left below its negative
+	Horizontal string `json:"horizontal,omitempty"`
+	// Invert swaps up/down and left/right
+	Invert bool `json:"invert,omitempty"`
+	// Threshold is the deflection that presses a direction (default 16384); it releases
+	// again below three quarters of it
+	Threshold int16 `json:"threshold,omitempty"`
+	// RepeatDelayMs and RepeatIntervalMs control auto-repeat while a direction is held
+	// (defaults 500 and 150)
+	RepeatDelayMs    int `json:"repeat_delay_ms,omitempty"`
+	RepeatIntervalMs int `json:"repeat_interval_ms,omitempty"`
+}
+
+// Validate checks the channels, threshold and timings
+func (d *DpadConfig) Validate() error {
+	if d.Vertical == "" && d.Horizontal == "" {
+		return fmt.Errorf("needs a vertical or horizontal channel")
+	}
+	for _, name := range []string{d.Vertical, d.Horizontal} {
+		if name == "" {
+			continue
+		}
+		if err := validateChannel(name); err != nil {
+			return err
+		}
+	}
+	if d.Threshold < 0 {
+		return fmt.Errorf("threshold %d must not be negative", d.Threshold)
+	}
+	if d.RepeatDelayMs < 0 || d.RepeatIntervalMs < 0 {
+		return fmt.Errorf("timings must not be negative")
+	}
+	if d.RepeatIntervalMs > 0 && d.RepeatIntervalMs < minRepeatIntervalMs {
+		return fmt.Errorf("repeat_interval_ms must be at least %d", minRepeatIntervalMs)
+	}
+	return nil
+}
+
+// Rules expands the D-pad into one auto-repeating button rule per direction
+func (d *DpadConfig) Rules() []ButtonRule {
+	threshold := d.Threshold
+	if threshold == 0 {
+		threshold = defaultDpadThreshold
+	}
+	exit := threshold / 4 * 3
+
+	var rules []ButtonRule
+	add := func(channel, positive, negative string) {
+		if channel == "" {
+			return
+		}
+		if d.Invert {
+			positive, negative = negative, positive
+		}
+		for _, dir := range []struct {
+			button      string
+			enter, exit int16
+		}{{positive, threshold, exit}, {negative, -threshold, -exit}} {
+			rules = append(rules, ButtonRule{
+				Channel:          channel,
+				Button:           dir.button,
+				Enter:            dir.enter,
+				Exit:             &dir.exit,
+				Mode:             ButtonRepeat,
+				RepeatDelayMs:    d.RepeatDelayMs,
+				RepeatIntervalMs: d.RepeatIntervalMs,
+			})
+		}
+	}
+	add(d.Vertical, "dpad_up", "dpad_down")
+	add(d.Horizontal, "dpad_right", "dpad_left")
+	return rules
+}
@@ -0,0 +1,199 @@
+package helper
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FailsafeMode selects what the translator outputs once RC data goes stale
+type FailsafeMode int
+
+const (
+	FailsafeHold        FailsafeMode = iota // keep the last received values
+	FailsafeCenter                          // center all sticks and the dial, releasing its buttons
+	FailsafeThrottleLow                     // center everything but pull the throttle channel fully down
+	FailsafeCustom                          // center everything but apply user supplied values
+)
+
+var failsafeModeNames = map[FailsafeMode]string{
+	FailsafeHold:        "hold",
+	FailsafeCenter:      "center",
+	FailsafeThrottleLow: "throttle-low",
+	FailsafeCustom:      "custom",
+}
+
+func (m FailsafeMode) String() string {
+	if name, ok := failsafeModeNames[m]; ok {
+		return name
+	}
+	return fmt.Sprintf("FailsafeMode(%d)", int(m))
+}
+
+// ParseFailsafeMode parses a mode name such as "center" or "throttle-low"
+func ParseFailsafeMode(name string) (FailsafeMode, error) {
+	for mode, n := range failsafeModeNames {
+		if strings.EqualFold(name, n) {
+			return mode, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown failsafe mode %q (want hold, center, throttle-low or custom)", name)
+}
+
+// ParseChannelValues parses a comma separated list of channel=value pairs, e.g. "left_vertical=-32768,camera_dial=0".
+// Names must be known channels or RC controls.
+func ParseChannelValues(list string) (map[string]int16, error) {
+	values := make(map[string]int16)
+	for _, item := range strings.Split(list, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		name, raw, ok := strings.Cut(item, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid channel value %q (want name=value)", item)
+		}
+		name = strings.TrimSpace(name)
+		if err := validateChannel(name); err != nil {
+			return nil, err
+		}
+		v, err := strconv.ParseInt(strings.TrimSpace(raw), 10, 16)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for channel %q: %w", name, err)
+		}
+		values[name] = int16(v)
+	}
+	return values, nil
+}
+
+// FailsafeState describes what the failsafe is currently doing to the outputs
+type FailsafeState int
+
+const (
+	FailsafeInactive   FailsafeState = iota // live data passes through
+	FailsafeActive                          // data is stale, failsafe values are output
+	FailsafeRecovering                      // data resumed, outputs glide back to live values
+)
+
+// Failsafe replaces channel values with safe ones when no fresh RC data arrived for a while
+// and blends back to live values once data resumes. It is safe for concurrent use.
+type Failsafe struct {
+	mu        sync.Mutex
+	mode      FailsafeMode
+	timeout   time.Duration
+	recovery  time.Duration
+	custom    map[string]int16
+	lastFresh time.Time
+	state     FailsafeState
+	resumed   time.Time
+	last      map[string]int16 // values output on the previous Apply
+	from      map[string]int16 // values output when data resumed
+}
+
+// NewFailsafe creates a failsafe that triggers after timeout without fresh data
+// and takes recovery to glide back to live values (0 = jump back immediately)
+func NewFailsafe(mode FailsafeMode, timeout, recovery time.Duration) *Failsafe {
+	return &Failsafe{
+		mode:      mode,
+		timeout:   timeout,
+		recovery:  recovery,
+		lastFresh: time.Now(),
+		last:      make(map[string]int16),
+		from:      make(map[string]int16),
+	}
+}
+
+// Mode returns the configured failsafe behavior
+func (f *Failsafe) Mode() FailsafeMode {
+	return f.mode
+}
+
+// SetCustomValues sets the values used in custom mode; channels not listed are centered
+func (f *Failsafe) SetCustomValues(values map[string]int16) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.custom = values
+}
+
+// Fresh registers that valid RC data arrived
+func (f *Failsafe) Fresh(at time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.lastFresh = at
+}
+
+// Stale reports whether the data is old enough at time now for Apply to output failsafe values
+func (f *Failsafe) Stale(now time.Time) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return now.Sub(f.lastFresh) > f.timeout
+}
+
+// Apply substitutes failsafe values into channels in place when data is stale and
+// returns the resulting state and how long the data has been stale
+func (f *Failsafe) Apply(now time.Time, channels map[string]int16) (FailsafeState, time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	stale := now.Sub(f.lastFresh)
+	switch {
+	case stale > f.timeout:
+		f.state = FailsafeActive
+		f.substitute(channels)
+	case f.state == FailsafeActive:
+		f.state = FailsafeRecovering
+		f.resumed = now
+		clear(f.from)
+		for name, v := range f.last {
+			f.from[name] = v
+		}
+		fallthrough
+	case f.state == FailsafeRecovering:
+		progress := 1.0
+		if f.recovery > 0 {
+			progress = float64(now.Sub(f.resumed)) / float64(f.recovery)
+		}
+		if progress >= 1 {
+			f.state = FailsafeInactive
+			break
+		}
+		for name, live := range channels {
+			if from, ok := f.from[name]; ok {
+				channels[name] = int16(float64(from) + (float64(live)-float64(from))*progress)
+			}
+		}
+	}
+
+	clear(f.last)
+	for name, v := range channels {
+		f.last[name] = v
+	}
+	return f.state, max(0, stale)
+}
+
+// substitute writes the failsafe values of the configured mode into channels
+func (f *Failsafe) substitute(channels map[string]int16) {
+	if f.mode == FailsafeHold {
+		return
+	}
+
+	for name := range channels {
+		channels[name] = 0
+	}
+	switch f.mode {
+	case FailsafeThrottleLow:
+		// Failsafe runs after the stick mode remap, so the throttle is on its Mode 2 channel
+		throttle := DefaultAxisChannels["throttle"]
+		if _, ok := channels[throttle]; ok {
+			channels[throttle] = -32768
+		}
+	case FailsafeCustom:
+		for name, v := range f.custom {
+			if _, ok := channels[name]; ok {
+				channels[name] = v
+			}
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package helper
+
+import (
+	"maps"
+	"testing"
+	"time"
+)
+
+func TestParseChannelValues(t *testing.T) {
+	tests := []struct {
+		list    string
+		want    map[string]int16
+		wantErr bool
+	}{
+		{"left_vertical=-32768, camera_dial=0", map[string]int16{"left_vertical": -32768, "camera_dial": 0}, false},
+		{"right_horizontal=32767,", map[string]int16{"right_horizontal": 32767}, false},
+		{"", map[string]int16{}, false},
+		{"left_vertcal=-32768", nil, true},
+		{"throttle=-32768", nil, true},
+		{"left_vertical", nil, true},
+		{"left_vertical=40000", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseChannelValues(tt.list)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%q: error %v, want error %v", tt.list, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !maps.Equal(got, tt.want) {
+			t.Errorf("%q: %v, want %v", tt.list, got, tt.want)
+		}
+	}
+}
+
+func TestFailsafeThrottleLow(t *testing.T) {
+	f := NewFailsafe(FailsafeThrottleLow, 100*time.Millisecond, 0)
+	now := time.Unix(1000, 0)
+	f.Fresh(now)
+	channels := map[string]int16{"left_vertical": 5000, "right_vertical": 7000}
+	if state, _ := f.Apply(now.Add(200*time.Millisecond), channels); state != FailsafeActive {
+		t.Fatalf("state %v, want active", state)
+	}
+	if channels["left_vertical"] != -32768 || channels["right_vertical"] != 0 {
+		t.Errorf("outputs %v, want throttle low and the rest centered", channels)
+	}
+}
@@ -0,0 +1,236 @@
+package helper
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Filter types
+const (
+	FilterLowPass = "lowpass"  // first-order low-pass, fixed cutoff
+	FilterOneEuro = "one_euro" // low-pass whose cutoff rises with stick speed
+	FilterMedian  = "median"   // median of the last samples, removes spikes
+)
+
+// Filter defaults
+const (
+	defaultCutoffHz       = 10
+	defaultMinCutoffHz    = 1
+	defaultOneEuroBeta    = 5
+	defaultDerivCutoffHz  = 1
+	defaultMedianWindow   = 5
+	maxMedianWindow       = 15
+	filterMaxSampleGap    = 250 * time.Millisecond
+	filterFullScale       = 32767.0
+	oneEuroMinSampleDelta = time.Millisecond
+)
+
+// FilterConfig smooths one channel on every RC frame. Smoothing always adds delay; Delay
+// estimates how much so pilots can trade smoothness against latency.
+type FilterConfig struct {
+	// Type is lowpass, one_euro or median
+	Type string `json:"type"`
+	// CutoffHz is the low-pass cutoff frequency (default 10)
+	CutoffHz float64 `json:"cutoff_hz,omitempty"`
+	// MinCutoffHz is the One Euro cutoff at rest (default 1); Beta raises it with stick speed
+	// in full deflections per second (default 5); DCutoffHz smooths that speed (default 1)
+	MinCutoffHz float64 `json:"min_cutoff_hz,omitempty"`
+	Beta        float64 `json:"beta,omitempty"`
+	DCutoffHz   float64 `json:"d_cutoff_hz,omitempty"`
+	// Window is the number of samples of the median filter, odd (default 5)
+	Window int `json:"window,omitempty"`
+}
+
+// Validate checks the filter type and parameters
+func (c *FilterConfig) Validate() error {
+	switch strings.ToLower(c.Type) {
+	case FilterLowPass, FilterOneEuro, FilterMedian:
+	default:
+		return fmt.Errorf("unknown filter type %q (want lowpass, one_euro or median)", c.Type)
+	}
+	if c.CutoffHz < 0 || c.MinCutoffHz < 0 || c.Beta < 0 || c.DCutoffHz < 0 {
+		return fmt.Errorf("cutoffs and beta must not be negative")
+	}
+	if c.Window < 0 || c.Window > maxMedianWindow || c.Window > 0 && c.Window%2 == 0 {
+		return fmt.Errorf("window must be an odd number up to %d", maxMedianWindow)
+	}
+	return nil
+}
+
+// withDefaults returns the config with unset parameters filled in
+func (c FilterConfig) withDefaults() FilterConfig {
+	c.Type = strings.ToLower(c.Type)
+	if c.CutoffHz == 0 {
+		c.CutoffHz = defaultCutoffHz
+	}
+	if c.MinCutoffHz == 0 {
+		c.MinCutoffHz = defaultMinCutoffHz
+	}
+	if c.Beta == 0 {
+		c.Beta = defaultOneEuroBeta
+	}
+	if c.DCutoffHz == 0 {
+		c.DCutoffHz = defaultDerivCutoffHz
+	}
+	if c.Window == 0 {
+		c.Window = defaultMedianWindow
+	}
+	return c
+}
+
+// Delay estimates the lag the filter adds to slow stick movements when frames arrive every
+// frameInterval. For One Euro this is the lag at rest; fast movements are delayed less.
+func (c FilterConfig) Delay(frameInterval time.Duration) time.Duration {
+	c = c.withDefaults()
+	var seconds float64
+	switch c.Type {
+	case FilterLowPass:
+		seconds = 1 / (2 * math.Pi * c.CutoffHz)
+	case FilterOneEuro:
+		seconds = 1 / (2 * math.Pi * c.MinCutoffHz)
+	}
+	return time.Duration(seconds*float64(time.Second)) + time.Duration(c.DelayFrames())*frameInterval
+}
+
+// DelayFrames returns the lag of a filter that is counted in frames rather than time: half the
+// window of a median filter, none for the others
+func (c FilterConfig) DelayFrames() int {
+	c = c.withDefaults()
+	if c.Type != FilterMedian {
+		return 0
+	}
+	return (c.Window - 1) / 2
+}
+
+func (c FilterConfig) String() string {
+	c = c.withDefaults()
+	switch c.Type {
+	case FilterLowPass:
+		return fmt.Sprintf("low-pass %g Hz", c.CutoffHz)
+	case FilterOneEuro:
+		return fmt.Sprintf("One Euro min cutoff %g Hz, beta %g", c.MinCutoffHz, c.Beta)
+	default:
+		return fmt.Sprintf("median of %d samples", c.Window)
+	}
+}
+
+// signalFilter smooths a stream of samples taken at the given times
+type signalFilter interface {
+	filter(t time.Time, v float64) float64
+}
+
+func newSignalFilter(c FilterConfig) signalFilter {
+	c = c.withDefaults()
+	switch c.Type {
+	case FilterLowPass:
+		return &lowPass{cutoff: c.CutoffHz}
+	case FilterOneEuro:
+		return &oneEuro{minCutoff: c.MinCutoffHz, beta: c.Beta, dx: lowPass{cutoff: c.DCutoffHz}}
+	default:
+		return &median{window: c.Window}
+	}
+}
+
+// smoothingFactor is the weight of a new sample for a first-order low-pass
+func smoothingFactor(dt time.Duration, cutoff float64) float64 {
+	tau := 1 / (2 * math.Pi * cutoff)
+	return dt.Seconds() / (dt.Seconds() + tau)
+}
+
+// lowPass is a first-order low-pass filter that adapts to the actual sample spacing
+type lowPass struct {
+	cutoff float64
+	last   time.Time
+	y      float64
+}
+
+func (f *lowPass) filter(t time.Time, v float64) float64 {
+	f.step(t, v, f.cutoff)
+	return f.y
+}
+
+// step adds a sample with the given cutoff. The first sample and samples after a gap are
+// taken as they are, so the filter does not glide in from stale values.
+func (f *lowPass) step(t time.Time, v, cutoff float64) {
+	dt := t.Sub(f.last)
+	if f.last.IsZero() || dt > filterMaxSampleGap {
+		f.y = v
+	} else if dt > 0 {
+		f.y += smoothingFactor(dt, cutoff) * (v - f.y)
+	}
+	f.last = t
+}
+
+// oneEuro is the 1€ filter (Casiez et al., 2012): slow movements are smoothed strongly, fast
+// ones pass with little lag
+type oneEuro struct {
+	minCutoff float64
+	beta      float64
+	x         lowPass
+	dx        lowPass // speed in full deflections per second
+}
+
+func (f *oneEuro) filter(t time.Time, v float64) float64 {
+	dt := t.Sub(f.x.last)
+	if f.x.last.IsZero() || dt > filterMaxSampleGap {
+		f.x.step(t, v, f.minCutoff)
+		f.dx.step(t, 0, f.dx.cutoff)
+		return f.x.y
+	}
+	if dt < oneEuroMinSampleDelta {
+		return f.x.y
+	}
+	f.dx.step(t, (v-f.x.y)/filterFullScale/dt.Seconds(), f.dx.cutoff)
+	f.x.step(t, v, f.minCutoff+f.beta*math.Abs(f.dx.y))
+	return f.x.y
+}
+
+// median returns the median of the last window samples
+type median struct {
+	window  int
+	samples []float64
+	sorted  []float64
+	last    time.Time
+}
+
+func (f *median) filter(t time.Time, v float64) float64 {
+	if !f.last.IsZero() && t.Sub(f.last) > filterMaxSampleGap {
+		f.samples = f.samples[:0]
+	}
+	f.last = t
+	if len(f.samples) == f.window {
+		f.samples = f.samples[1:]
+	}
+	f.samples = append(f.samples, v)
+	f.sorted = append(f.sorted[:0], f.samples...)
+	slices.Sort(f.sorted)
+	return f.sorted[len(f.sorted)/2]
+}
+
+// FilterBank smooths channels with their filters. It keeps state between samples, so Apply
+// must only be called from one goroutine, once per RC frame.
+type FilterBank struct {
+	filters map[string]signalFilter
+}
+
+// NewFilterBank compiles validated filters by channel
+func NewFilterBank(configs map[string]FilterConfig) *FilterBank {
+	b := &FilterBank{filters: make(map[string]signalFilter, len(configs))}
+	for name, c := range configs {
+		b.filters[name] = newSignalFilter(c)
+	}
+	return b
+}
+
+// Apply returns the filtered value of a channel sampled at time t. Channels without a filter
+// pass unchanged.
+func (b *FilterBank) Apply(t time.Time, name string, v int16) int16 {
+	f, ok := b.filters[name]
+	if !ok {
+		return v
+	}
+	return ClampAxis(int32(math.Round(f.filter(t, float64(v)))))
+}
@@ -0,0 +1,115 @@
+package helper
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// runFilter feeds the values to a filter one frame apart and returns its outputs
+func runFilter(c FilterConfig, interval time.Duration, values ...int16) []int16 {
+	b := NewFilterBank(map[string]FilterConfig{"camera_dial": c})
+	t := time.Unix(1000, 0)
+	out := make([]int16, len(values))
+	for i, v := range values {
+		out[i] = b.Apply(t, "camera_dial", v)
+		t = t.Add(interval)
+	}
+	return out
+}
+
+func TestFilterDelay(t *testing.T) {
+	tests := []struct {
+		cfg      FilterConfig
+		interval time.Duration
+		frames   int
+		want     time.Duration
+	}{
+		{FilterConfig{Type: "lowpass"}, 10 * time.Millisecond, 0, 15915 * time.Microsecond},
+		{FilterConfig{Type: "one_euro"}, 10 * time.Millisecond, 0, 159155 * time.Microsecond},
+		{FilterConfig{Type: "median"}, 10 * time.Millisecond, 2, 20 * time.Millisecond},
+		{FilterConfig{Type: "median", Window: 3}, 15 * time.Millisecond, 1, 15 * time.Millisecond},
+		// Not measured yet: only the frame count is known
+		{FilterConfig{Type: "median"}, 0, 2, 0},
+	}
+	for _, tt := range tests {
+		if got := tt.cfg.DelayFrames(); got != tt.frames {
+			t.Errorf("%v: %d frame(s) delay, want %d", tt.cfg, got, tt.frames)
+		}
+		if got := tt.cfg.Delay(tt.interval).Round(time.Microsecond); got != tt.want {
+			t.Errorf("%v at %v: delay %v, want %v", tt.cfg, tt.interval, got, tt.want)
+		}
+	}
+}
+
+func TestMedianFilterRemovesSpikes(t *testing.T) {
+	got := runFilter(FilterConfig{Type: "median", Window: 3}, 10*time.Millisecond, 100, 100, 30000, 100, 100, -30000, 100)
+	for i, v := range got {
+		if v != 100 {
+			t.Errorf("sample %d: %d, want 100 (all: %v)", i, v, got)
+		}
+	}
+}
+
+func TestLowPassFilter(t *testing.T) {
+	ms := 10 * time.Millisecond
+	got := runFilter(FilterConfig{Type: "lowpass", CutoffHz: 10}, ms, 1000, 2000, 2000)
+	// The first sample passes unchanged, the next move by dt / (dt + 1/(2π·10 Hz))
+	alpha := 0.01 / (0.01 + 1/(2*math.Pi*10))
+	want := []int16{1000, int16(math.Round(1000 + 1000*alpha)), int16(math.Round(1000 + 1000*(1-(1-alpha)*(1-alpha))))}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("sample %d: %d, want %d", i, got[i], want[i])
+		}
+	}
+
+	// After a gap the filter restarts from the new value instead of gliding from the old one
+	if got := runFilter(FilterConfig{Type: "lowpass"}, 300*time.Millisecond, 1000, 5000); got[1] != 5000 {
+		t.Errorf("after a gap: %d, want 5000", got[1])
+	}
+}
+
+func TestOneEuroFilterFollowsFastMovesCloser(t *testing.T) {
+	cfg := FilterConfig{Type: "one_euro", MinCutoffHz: 1, Beta: 5}
+	ramp := func(step int16) []int16 {
+		values := make([]int16, 20)
+		for i := range values {
+			values[i] = int16(i) * step
+		}
+		return values
+	}
+	lag := func(step int16) float64 {
+		values := ramp(step)
+		got := runFilter(cfg, 10*time.Millisecond, values...)
+		last := len(values) - 1
+		return float64(values[last]-got[last]) / float64(step)
+	}
+	// Lag in frames: a slow ramp is smoothed at the minimum cutoff, a fast one much less
+	slow, fast := lag(10), lag(1500)
+	if fast >= slow/2 {
+		t.Errorf("fast ramp lags %.1f frames, slow ramp %.1f; want fast well below slow", fast, slow)
+	}
+
+	if got := runFilter(cfg, 10*time.Millisecond, 4000, 4000, 4000); got[2] != 4000 {
+		t.Errorf("steady input drifted to %d", got[2])
+	}
+}
+
+func TestLatencyTrackerFrameInterval(t *testing.T) {
+	tr := NewLatencyTracker()
+	if got := tr.FrameInterval(); got != 0 {
+		t.Fatalf("interval %v before any replies, want 0", got)
+	}
+	start := time.Unix(1000, 0)
+	for i, gap := range []int{0, 10, 10, 11, 9, 10, 40, 10} {
+		start = start.Add(time.Duration(gap) * time.Millisecond)
+		tr.RecordReply(FrameStamps{ReplyComplete: start})
+		if i == 0 && tr.FrameInterval() != 0 {
+			t.Fatal("interval measured from a single reply")
+		}
+	}
+	// Histogram buckets are within about 1.6% of the recorded value
+	if got := tr.FrameInterval(); got < 9800*time.Microsecond || got > 10200*time.Microsecond {
+		t.Errorf("interval %v, want the median of about 10ms", got)
+	}
+}
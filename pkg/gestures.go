@@ -0,0 +1,252 @@
+package helper
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/CB2Moon/vgamepad-go/pkg/commons"
+)
+
+// Gesture defaults
+const (
+	defaultGestureHold   = time.Second
+	defaultFlickCount    = 2
+	defaultFlickWindow   = 600 * time.Millisecond
+	defaultGesturePulse  = 100 * time.Millisecond
+	gesturePulseMinSpace = 50 * time.Millisecond
+)
+
+// GestureConfig describes a stick gesture and what it triggers. A gesture is either a set of
+// conditions held for HoldMs, e.g. a stick in a corner, or Count flicks of one channel within
+// WindowMs, e.g. a throttle double-flick.
+type GestureConfig struct {
+	Name string `json:"name"`
+	// Conditions must all be met for HoldMs (default 1000)
+	Conditions []GestureCondition `json:"conditions,omitempty"`
+	HoldMs     int                `json:"hold_ms,omitempty"`
+	// Flick must happen Count times (default 2) within WindowMs (default 600)
+	Flick    *FlickConfig `json:"flick,omitempty"`
+	Count    int          `json:"count,omitempty"`
+	WindowMs int          `json:"window_ms,omitempty"`
+
+	Action GestureAction `json:"action"`
+}
+
+// GestureCondition requires a channel to be at or above Above and/or at or below Below
+type GestureCondition struct {
+	Channel string `json:"channel"`
+	Above   *int16 `json:"above,omitempty"`
+	Below   *int16 `json:"below,omitempty"`
+}
+
+// GestureAction is what a recognized gesture does. The translator carries out profile
+// switches and recentering; button presses, menu toggles and macros are handled by the mapper.
+type GestureAction struct {
+	// Press pulses a button for PulseMs (default 100)
+	Press   string `json:"press,omitempty"`
+	PulseMs int    `json:"pulse_ms,omitempty"`
+	// Profile switches to another mapping profile
+	Profile string `json:"profile,omitempty"`
+	// Recenter takes the stick positions after the sticks are released as their new centers
+	Recenter bool `json:"recenter,omitempty"`
+	// ToggleMenu switches between the flight and menu mapping
+	ToggleMenu bool `json:"toggle_menu,omitempty"`
+	// Macro runs a macro of the profile, or cancels it if it is running
+	Macro string `json:"macro,omitempty"`
+}
+
+func (a GestureAction) String() string {
+	var parts []string
+	if a.Press != "" {
+		parts = append(parts, "press "+a.Press)
+	}
+	if a.Profile != "" {
+		parts = append(parts, "switch to profile "+a.Profile)
+	}
+	if a.Recenter {
+		parts = append(parts, "recenter sticks")
+	}
+	if a.ToggleMenu {
+		parts = append(parts, "toggle menu mode")
+	}
+	if a.Macro != "" {
+		parts = append(parts, "run macro "+a.Macro)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Validate checks the gesture definition and its action
+func (g *GestureConfig) Validate() error {
+	if g.Name == "" {
+		return fmt.Errorf("needs a name")
+	}
+	if len(g.Conditions) > 0 == (g.Flick != nil) {
+		return fmt.Errorf("%s: needs either conditions or a flick", g.Name)
+	}
+	for _, c := range g.Conditions {
+		if err := validateChannel(c.Channel); err != nil {
+			return fmt.Errorf("%s: %w", g.Name, err)
+		}
+		if c.Above == nil && c.Below == nil {
+			return fmt.Errorf("%s: condition on %s needs above or below", g.Name, c.Channel)
+		}
+	}
+	if g.Flick != nil {
+		if err := g.Flick.Validate(); err != nil {
+			return fmt.Errorf("%s: %w", g.Name, err)
+		}
+	}
+	if g.HoldMs < 0 || g.WindowMs < 0 || g.Count < 0 || g.Action.PulseMs < 0 {
+		return fmt.Errorf("%s: timings and count must not be negative", g.Name)
+	}
+
+	a := g.Action
+	if a.Press == "" && a.Profile == "" && !a.Recenter && !a.ToggleMenu && a.Macro == "" {
+		return fmt.Errorf("%s: action needs press, profile, recenter, toggle_menu or macro", g.Name)
+	}
+	if a.Press != "" {
+		if _, err := ParseButton(a.Press); err != nil {
+			return fmt.Errorf("%s: %w", g.Name, err)
+		}
+	}
+	return nil
+}
+
+// met reports whether the channel value satisfies the condition
+func (c *GestureCondition) met(channels map[string]int16) bool {
+	v, ok := channels[c.Channel]
+	if !ok {
+		return false
+	}
+	return (c.Above == nil || v >= *c.Above) && (c.Below == nil || v <= *c.Below)
+}
+
+// gesture is a compiled GestureConfig with its runtime state
+type gesture struct {
+	cfg    GestureConfig
+	button commons.XUSBButton
+	pulse  time.Duration
+
+	// Hold gestures
+	hold  time.Duration
+	since time.Time
+	fired bool
+
+	// Flick gestures
+	flick  *flickDetector
+	need   int
+	count  int
+	window time.Duration
+	first  time.Time
+}
+
+// update feeds the gesture the current channel values and reports whether it was just recognized
+func (g *gesture) update(now time.Time, channels map[string]int16) bool {
+	if g.flick != nil {
+		if !g.flick.update(now, channels[g.flick.channel]) {
+			return false
+		}
+		if g.count == 0 || now.Sub(g.first) > g.window {
+			g.count, g.first = 0, now
+		}
+		g.count++
+		if g.count < g.need {
+			return false
+		}
+		g.count = 0
+		return true
+	}
+
+	for i := range g.cfg.Conditions {
+		if !g.cfg.Conditions[i].met(channels) {
+			g.since, g.fired = time.Time{}, false
+			return false
+		}
+	}
+	if g.since.IsZero() {
+		g.since = now
+	}
+	if g.fired || now.Sub(g.since) < g.hold {
+		return false
+	}
+	g.fired = true
+	return true
+}
+
+// reset drops a gesture in progress. Like a fired hold, it has to be let go of before it can
+// be recognized again.
+func (g *gesture) reset() {
+	g.fired, g.count = true, 0
+	if g.flick != nil {
+		g.flick.out, g.flick.reached = true, false
+	}
+}
+
+// GestureRecognizer watches the decoded stick stream for gestures. Button actions are pulsed
+// by the recognizer itself; other actions are returned to the caller.
+type GestureRecognizer struct {
+	gestures []*gesture
+	pulses   map[commons.XUSBButton]time.Time
+}
+
+// NewGestureRecognizer compiles validated gesture definitions
+func NewGestureRecognizer(configs []GestureConfig) *GestureRecognizer {
+	r := &GestureRecognizer{pulses: make(map[commons.XUSBButton]time.Time)}
+	for _, cfg := range configs {
+		g := &gesture{
+			cfg:    cfg,
+			pulse:  msOrDefault(cfg.Action.PulseMs, defaultGesturePulse),
+			hold:   msOrDefault(cfg.HoldMs, defaultGestureHold),
+			window: msOrDefault(cfg.WindowMs, defaultFlickWindow),
+		}
+		if cfg.Action.Press != "" {
+			g.button, _ = ParseButton(cfg.Action.Press)
+		}
+		if cfg.Flick != nil {
+			g.flick = newFlickDetector(*cfg.Flick)
+			g.need = cfg.Count
+			if g.need == 0 {
+				g.need = defaultFlickCount
+			}
+		}
+		r.gestures = append(r.gestures, g)
+	}
+	return r
+}
+
+// Update feeds all gestures the current channel values and returns the ones just recognized
+func (r *GestureRecognizer) Update(now time.Time, channels map[string]int16) []GestureConfig {
+	var recognized []GestureConfig
+	for _, g := range r.gestures {
+		if !g.update(now, channels) {
+			continue
+		}
+		if g.button != 0 {
+			// Leave a gap after a previous pulse so back-to-back presses are seen as two
+			if until, ok := r.pulses[g.button]; !ok || now.After(until.Add(gesturePulseMinSpace)) {
+				r.pulses[g.button] = now.Add(g.pulse)
+			}
+		}
+		recognized = append(recognized, g.cfg)
+	}
+	return recognized
+}
+
+// Reset drops gestures in progress, for example when the stick stream was interrupted
+func (r *GestureRecognizer) Reset() {
+	for _, g := range r.gestures {
+		g.reset()
+	}
+}
+
+// Buttons returns the buttons currently pulsed by recognized gestures
+func (r *GestureRecognizer) Buttons(now time.Time) commons.XUSBButton {
+	var buttons commons.XUSBButton
+	for b, until := range r.pulses {
+		if now.Before(until) {
+			buttons |= b
+		}
+	}
+	return buttons
+}
@@ -0,0 +1,27 @@
+package helper
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGestureResetNeedsRelease(t *testing.T) {
+	r := NewGestureRecognizer([]GestureConfig{{Name: "pause", HoldMs: 500,
+		Action:     GestureAction{Press: "start"},
+		Conditions: []GestureCondition{{Channel: "left_vertical", Below: ptr16(-30000)}}}})
+	held := map[string]int16{"left_vertical": -32768}
+	released := map[string]int16{"left_vertical": 0}
+	start := time.Unix(1000, 0)
+	at := func(ms int) time.Time { return start.Add(time.Duration(ms) * time.Millisecond) }
+
+	r.Update(at(0), held)
+	r.Reset()
+	if got := r.Update(at(600), held); len(got) != 0 {
+		t.Fatal("gesture held through a reset fired")
+	}
+	r.Update(at(700), released)
+	r.Update(at(800), held)
+	if got := r.Update(at(1300), held); len(got) != 1 {
+		t.Fatal("gesture not recognized after being let go of")
+	}
+}
@@ -2,10 +2,10 @@ package helper
 
 import (
 	"encoding/binary"
+	"errors"
 	"fmt"
+	"io"
 	"time"
-
-	"go.bug.st/serial"
 )
 
 // CRC tables for DUML protocol
@@ -87,8 +87,18 @@ func CalcPkt55HdrChecksum(seed byte, packet []byte, plength int) byte {
 	return chksum
 }
 
-// ReadBytes reads exact number of bytes from port, handling partial reads
-func ReadBytes(port serial.Port, buffer []byte, count int) (int, error) {
+// ErrReadTimeout is returned when the port delivers no data before the read deadline
+var ErrReadTimeout = errors.New("read timeout")
+
+// ReadBytes reads exact number of bytes from port, handling partial reads.
+// The port's read timeout (serial.Port.SetReadTimeout) should be shorter than timeout,
+// otherwise a silent port blocks until its own timeout expires. A zero timeout waits forever.
+func ReadBytes(port io.Reader, buffer []byte, count int, timeout time.Duration) (int, error) {
+	var deadline time.Time
+	if timeout > 0 {
+		deadline = time.Now().Add(timeout)
+	}
+
 	bytesRead := 0
 	for bytesRead < count {
 		n, err := port.Read(buffer[bytesRead:count])
@@ -96,6 +106,9 @@ func ReadBytes(port serial.Port, buffer []byte, count int) (int, error) {
 			return bytesRead, err
 		}
 		if n == 0 {
+			if !deadline.IsZero() && time.Now().After(deadline) {
+				return bytesRead, ErrReadTimeout
+			}
 			time.Sleep(5 * time.Millisecond)
 			continue
 		}
@@ -104,35 +117,35 @@ func ReadBytes(port serial.Port, buffer []byte, count int) (int, error) {
 	return bytesRead, nil
 }
 
-// ReadPacketHeader reads and validates the packet header
-func ReadPacketHeader(port serial.Port, buffer []byte) ([]byte, uint16, error) {
+// ReadPacketHeader reads and validates the packet header.
+// A zero timeout waits forever for the rest of the header once the start byte arrived.
+func ReadPacketHeader(port io.Reader, buffer []byte, timeout time.Duration) ([]byte, uint16, error) {
 	packetBuffer := make([]byte, 0, 64)
 
 	// Read start byte
 	n, err := port.Read(buffer[:1])
-	if err != nil || n == 0 || buffer[0] != 0x55 {
+	if err != nil {
+		return nil, 0, err
+	}
+	if n == 0 {
+		return nil, 0, ErrReadTimeout
+	}
+	if buffer[0] != 0x55 {
 		return nil, 0, fmt.Errorf("invalid start byte")
 	}
 	packetBuffer = append(packetBuffer, buffer[0])
 
-	// Read length bytes
-	n, err = ReadBytes(port, buffer, 2)
-	if err != nil || n != 2 {
-		return nil, 0, fmt.Errorf("failed to read header: %v", err)
+	// Read length bytes and header checksum
+	n, err = ReadBytes(port, buffer, 3, timeout)
+	if err != nil || n != 3 {
+		return nil, 0, fmt.Errorf("failed to read header: %w", err)
 	}
-	packetBuffer = append(packetBuffer, buffer[:2]...)
+	packetBuffer = append(packetBuffer, buffer[:3]...)
 
 	// Parse packet length
 	packetHeader := binary.LittleEndian.Uint16(buffer[:2])
 	packetLength := packetHeader & 0x03FF
 
-	// Read header checksum
-	n, err = port.Read(buffer[:1])
-	if err != nil || n != 1 {
-		return nil, 0, fmt.Errorf("failed to read checksum: %v", err)
-	}
-	packetBuffer = append(packetBuffer, buffer[0])
-
 	return packetBuffer, packetLength, nil
 }
 
@@ -0,0 +1,247 @@
+package helper
+
+import (
+	"fmt"
+	"math/bits"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Histogram precision: every power-of-two range is split into 64 linear
+// sub-buckets, which keeps the relative error of any recorded value below ~1.6%
+const (
+	histogramSubBucketBits  = 7
+	histogramSubBucketCount = 1 << histogramSubBucketBits
+	histogramSubBucketHalf  = histogramSubBucketCount / 2
+	histogramMaxMicros      = uint64(60 * time.Second / time.Microsecond)
+)
+
+// Histogram is a small HDR-style (log-linear) histogram of durations with microsecond resolution.
+// Values above one minute are clamped into the highest bucket.
+type Histogram struct {
+	counts []uint64
+	count  uint64
+	sum    uint64
+	min    uint64
+	max    uint64
+}
+
+// NewHistogram creates an empty histogram
+func NewHistogram() *Histogram {
+	return &Histogram{counts: make([]uint64, histogramBucketIndex(histogramMaxMicros)+1)}
+}
+
+// histogramBucketIndex maps a value in microseconds to its bucket
+func histogramBucketIndex(v uint64) int {
+	shift := bits.Len64(v) - histogramSubBucketBits
+	if shift <= 0 {
+		return int(v)
+	}
+	return shift*histogramSubBucketHalf + int(v>>shift)
+}
+
+// histogramBucketUpper returns the highest value that falls into the given bucket
+func histogramBucketUpper(idx int) uint64 {
+	if idx < histogramSubBucketCount {
+		return uint64(idx)
+	}
+	shift := idx/histogramSubBucketHalf - 1
+	sub := uint64(idx - shift*histogramSubBucketHalf)
+	return (sub+1)<<shift - 1
+}
+
+// Record adds a duration to the histogram; negative durations are recorded as zero
+func (h *Histogram) Record(d time.Duration) {
+	v := uint64(0)
+	if d > 0 {
+		v = uint64(d / time.Microsecond)
+	}
+	if v > histogramMaxMicros {
+		v = histogramMaxMicros
+	}
+
+	h.counts[histogramBucketIndex(v)]++
+	if h.count == 0 || v < h.min {
+		h.min = v
+	}
+	if v > h.max {
+		h.max = v
+	}
+	h.count++
+	h.sum += v
+}
+
+// Count returns the number of recorded values
+func (h *Histogram) Count() uint64 {
+	return h.count
+}
+
+// Min returns the smallest recorded value
+func (h *Histogram) Min() time.Duration {
+	return time.Duration(h.min) * time.Microsecond
+}
+
+// Max returns the largest recorded value
+func (h *Histogram) Max() time.Duration {
+	return time.Duration(h.max) * time.Microsecond
+}
+
+// Mean returns the average of all recorded values
+func (h *Histogram) Mean() time.Duration {
+	if h.count == 0 {
+		return 0
+	}
+	return time.Duration(h.sum/h.count) * time.Microsecond
+}
+
+// Percentile returns the value below which the given percentage (0-100) of recorded values fall
+func (h *Histogram) Percentile(p float64) time.Duration {
+	if h.count == 0 {
+		return 0
+	}
+	if p >= 100 {
+		return h.Max()
+	}
+
+	target := uint64(p / 100 * float64(h.count))
+	if target == 0 {
+		target = 1
+	}
+
+	var seen uint64
+	for idx, c := range h.counts {
+		seen += c
+		if seen >= target {
+			v := histogramBucketUpper(idx)
+			if v > h.max {
+				v = h.max
+			}
+			if v < h.min {
+				v = h.min
+			}
+			return time.Duration(v) * time.Microsecond
+		}
+	}
+	return h.Max()
+}
+
+// Reset clears all recorded values
+func (h *Histogram) Reset() {
+	clear(h.counts)
+	h.count, h.sum, h.min, h.max = 0, 0, 0, 0
+}
+
+// String formats the histogram as a one-line summary
+func (h *Histogram) String() string {
+	if h.count == 0 {
+		return "no samples"
+	}
+	return fmt.Sprintf("n=%d min=%v p50=%v p90=%v p99=%v p99.9=%v max=%v mean=%v",
+		h.count, h.Min(), h.Percentile(50), h.Percentile(90), h.Percentile(99), h.Percentile(99.9), h.Max(), h.Mean())
+}
+
+// FrameStamps holds the timestamps taken while one channel reply travels through the translator
+type FrameStamps struct {
+	PollSent      time.Time // channel request written to the RC
+	ReplyComplete time.Time // last byte of the reply frame read
+	Decoded       time.Time // stick values extracted from the frame
+	Mapped        time.Time // values mapped to gamepad outputs
+	SinkUpdated   time.Time // virtual gamepad report submitted
+}
+
+// LatencyTracker collects round-trip, reply interval, inter-arrival jitter and pipeline
+// latency histograms. It is safe for concurrent use.
+type LatencyTracker struct {
+	mu           sync.Mutex
+	roundTrip    *Histogram
+	interval     *Histogram
+	jitter       *Histogram
+	pipeline     *Histogram
+	lastArrival  time.Time
+	lastInterval time.Duration
+	started      time.Time
+}
+
+// NewLatencyTracker creates a tracker with empty histograms
+func NewLatencyTracker() *LatencyTracker {
+	return &LatencyTracker{
+		roundTrip: NewHistogram(),
+		interval:  NewHistogram(),
+		jitter:    NewHistogram(),
+		pipeline:  NewHistogram(),
+		started:   time.Now(),
+	}
+}
+
+// RecordReply records the round-trip time of a poll and the interval and inter-arrival jitter
+// of its reply. Jitter is the change between two consecutive reply intervals (RFC 3550 style,
+// unsmoothed).
+func (t *LatencyTracker) RecordReply(stamps FrameStamps) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !stamps.PollSent.IsZero() {
+		t.roundTrip.Record(stamps.ReplyComplete.Sub(stamps.PollSent))
+	}
+
+	if !t.lastArrival.IsZero() {
+		interval := stamps.ReplyComplete.Sub(t.lastArrival)
+		t.interval.Record(interval)
+		if t.lastInterval != 0 {
+			t.jitter.Record((interval - t.lastInterval).Abs())
+		}
+		t.lastInterval = interval
+	}
+	t.lastArrival = stamps.ReplyComplete
+}
+
+// FrameInterval returns the median time between replies, or 0 before two replies arrived
+func (t *LatencyTracker) FrameInterval() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.interval.Percentile(50)
+}
+
+// RecordPipeline records the time from a complete reply frame to the gamepad update that carried it
+func (t *LatencyTracker) RecordPipeline(stamps FrameStamps) {
+	if stamps.ReplyComplete.IsZero() || stamps.SinkUpdated.IsZero() {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.pipeline.Record(stamps.SinkUpdated.Sub(stamps.ReplyComplete))
+}
+
+// Reset clears all histograms and restarts the measurement window
+func (t *LatencyTracker) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.roundTrip.Reset()
+	t.interval.Reset()
+	t.jitter.Reset()
+	t.pipeline.Reset()
+	t.lastArrival = time.Time{}
+	t.lastInterval = 0
+	t.started = time.Now()
+}
+
+// Summary returns a multi-line report of all histograms
+func (t *LatencyTracker) Summary() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var sb strings.Builder
+	elapsed := time.Since(t.started).Round(time.Second)
+	fmt.Fprintf(&sb, "Latency over %v", elapsed)
+	if seconds := elapsed.Seconds(); seconds > 0 {
+		fmt.Fprintf(&sb, " (%.1f replies/s)", float64(t.roundTrip.Count())/seconds)
+	}
+	fmt.Fprintf(&sb, "\n  round trip: %v", t.roundTrip)
+	fmt.Fprintf(&sb, "\n  interval:   %v", t.interval)
+	fmt.Fprintf(&sb, "\n  jitter:     %v", t.jitter)
+	fmt.Fprintf(&sb, "\n  pipeline:   %v", t.pipeline)
+	return sb.String()
+}
@@ -0,0 +1,82 @@
+package helper
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHistogramBuckets(t *testing.T) {
+	// Every value falls into a bucket whose range contains it and is at most ~1.6% wide
+	for v := uint64(0); v <= histogramMaxMicros; v = v*21/20 + 1 {
+		idx := histogramBucketIndex(v)
+		upper := histogramBucketUpper(idx)
+		if upper < v {
+			t.Fatalf("value %d in bucket %d ending at %d", v, idx, upper)
+		}
+		if idx > 0 {
+			lower := histogramBucketUpper(idx-1) + 1
+			if lower > v {
+				t.Fatalf("value %d in bucket %d starting at %d", v, idx, lower)
+			}
+			if float64(upper-lower) > 0.016*float64(v) {
+				t.Fatalf("bucket %d of value %d spans %d to %d", idx, v, lower, upper)
+			}
+		}
+	}
+}
+
+func TestHistogramPercentiles(t *testing.T) {
+	h := NewHistogram()
+	if h.Percentile(50) != 0 || h.Mean() != 0 || h.String() != "no samples" {
+		t.Fatalf("empty histogram: p50 %v, mean %v, %q", h.Percentile(50), h.Mean(), h)
+	}
+
+	// Small values are recorded exactly
+	for us := 1; us <= 100; us++ {
+		h.Record(time.Duration(us) * time.Microsecond)
+	}
+	us := time.Microsecond
+	for _, c := range []struct {
+		p    float64
+		want time.Duration
+	}{{0, 1 * us}, {1, 1 * us}, {50, 50 * us}, {90, 90 * us}, {99, 99 * us}, {99.9, 99 * us}, {100, 100 * us}} {
+		if got := h.Percentile(c.p); got != c.want {
+			t.Errorf("p%g = %v, want %v", c.p, got, c.want)
+		}
+	}
+	if h.Count() != 100 || h.Min() != us || h.Max() != 100*us || h.Mean() != 50*us {
+		t.Errorf("count %d, min %v, max %v, mean %v", h.Count(), h.Min(), h.Max(), h.Mean())
+	}
+
+	// Larger values within the bucket precision
+	h.Reset()
+	for ms := 1; ms <= 1000; ms++ {
+		h.Record(time.Duration(ms) * time.Millisecond)
+	}
+	for _, c := range []struct {
+		p    float64
+		want time.Duration
+	}{{50, 500 * time.Millisecond}, {90, 900 * time.Millisecond}, {99, 990 * time.Millisecond}} {
+		got := h.Percentile(c.p)
+		if got < c.want || float64(got-c.want) > 0.016*float64(c.want) {
+			t.Errorf("p%g = %v, want %v to 1.6%% above", c.p, got, c.want)
+		}
+	}
+}
+
+func TestHistogramStaysWithinRecordedRange(t *testing.T) {
+	h := NewHistogram()
+	h.Record(12345 * time.Microsecond)
+	for _, p := range []float64{0, 50, 99.9, 100} {
+		if got := h.Percentile(p); got != 12345*time.Microsecond {
+			t.Errorf("single value: p%g = %v, want 12.345ms", p, got)
+		}
+	}
+
+	h.Reset()
+	h.Record(-time.Second)
+	h.Record(2 * time.Minute)
+	if h.Min() != 0 || h.Max() != time.Minute {
+		t.Errorf("clamped min %v, max %v, want 0 and 1m0s", h.Min(), h.Max())
+	}
+}
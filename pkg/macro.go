@@ -0,0 +1,190 @@
+package helper
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/CB2Moon/vgamepad-go/pkg/commons"
+)
+
+// macroTakeover is how far the pilot has to move a stick a macro overrides, from where it was
+// when the macro started, to take it back
+const macroTakeover = 16384
+
+// MacroConfig is a named sequence of gamepad states, e.g. Back, A, a short wait and A again to
+// restart a session. A macro runs when its channel crosses Enter or from a gesture; starting it
+// again while it runs cancels it.
+type MacroConfig struct {
+	Name string `json:"name"`
+	// Channel and Enter optionally bind the macro to an RC input, e.g. a button or a dial end.
+	// The input has to return inside half of Enter before it can trigger again.
+	Channel string      `json:"channel,omitempty"`
+	Enter   int16       `json:"enter,omitempty"`
+	Steps   []MacroStep `json:"steps"`
+}
+
+// MacroStep holds a gamepad state for Ms milliseconds. Buttons not listed are released, so an
+// empty step is a pause. Axes override stick axes (left_x, left_y, right_x, right_y) for the
+// step; the other axes keep following the sticks.
+type MacroStep struct {
+	Buttons []string         `json:"buttons,omitempty"`
+	Axes    map[string]int16 `json:"axes,omitempty"`
+	Ms      int              `json:"ms"`
+}
+
+// Validate checks the macro's binding and steps
+func (c *MacroConfig) Validate() error {
+	if c.Name == "" {
+		return fmt.Errorf("needs a name")
+	}
+	if c.Channel != "" {
+		if err := validateChannel(c.Channel); err != nil {
+			return fmt.Errorf("%s: %w", c.Name, err)
+		}
+		if c.Enter == 0 {
+			return fmt.Errorf("%s: enter threshold must not be 0", c.Name)
+		}
+	}
+	if len(c.Steps) == 0 {
+		return fmt.Errorf("%s: needs at least one step", c.Name)
+	}
+	for i, s := range c.Steps {
+		if s.Ms <= 0 {
+			return fmt.Errorf("%s: step %d: ms must be positive", c.Name, i+1)
+		}
+		for _, b := range s.Buttons {
+			if _, err := ParseButton(b); err != nil {
+				return fmt.Errorf("%s: step %d: %w", c.Name, i+1, err)
+			}
+		}
+		for name := range s.Axes {
+			if _, ok := axisOutputs[strings.ToLower(name)]; !ok {
+				return fmt.Errorf("%s: step %d: %q is not a stick axis", c.Name, i+1, name)
+			}
+		}
+	}
+	return nil
+}
+
+// macroStep is a compiled MacroStep
+type macroStep struct {
+	buttons  commons.XUSBButton
+	axes     map[int]int16
+	duration time.Duration
+}
+
+// macro is a compiled MacroConfig with the state of its binding
+type macro struct {
+	name  string
+	steps []macroStep
+	// Binding, see shiftLayer
+	bind *shiftLayer
+}
+
+func newMacro(c MacroConfig) *macro {
+	m := &macro{name: c.Name}
+	if c.Channel != "" {
+		m.bind = &shiftLayer{channel: c.Channel, enter: c.Enter}
+	}
+	for _, s := range c.Steps {
+		step := macroStep{axes: make(map[int]int16), duration: time.Duration(s.Ms) * time.Millisecond}
+		for _, name := range s.Buttons {
+			b, _ := ParseButton(name)
+			step.buttons |= b
+		}
+		for name, v := range s.Axes {
+			step.axes[axisOutputs[strings.ToLower(name)]] = v
+		}
+		m.steps = append(m.steps, step)
+	}
+	return m
+}
+
+// MacroPlayer runs one macro at a time on top of the live gamepad report
+type MacroPlayer struct {
+	macros  []*macro
+	running *macro
+	step    int       // index of the current step
+	since   time.Time // when the current step started
+	// Live axes at the first update of the running macro, for the takeover check
+	origin   [axisCount]int16
+	anchored bool
+}
+
+// NewMacroPlayer compiles validated macro definitions
+func NewMacroPlayer(configs []MacroConfig) *MacroPlayer {
+	p := &MacroPlayer{}
+	for _, c := range configs {
+		p.macros = append(p.macros, newMacro(c))
+	}
+	return p
+}
+
+// Running returns the name of the running macro, or "" if none is running
+func (p *MacroPlayer) Running() string {
+	if p.running == nil {
+		return ""
+	}
+	return p.running.name
+}
+
+// Trigger starts the named macro, or cancels it if it is already running. A different running
+// macro is replaced.
+func (p *MacroPlayer) Trigger(now time.Time, name string) {
+	if p.running != nil && p.running.name == name {
+		p.Cancel()
+		return
+	}
+	for _, m := range p.macros {
+		if m.name == name {
+			p.running, p.step, p.since, p.anchored = m, 0, now, false
+			return
+		}
+	}
+}
+
+// Cancel stops the running macro, releasing its buttons and axes
+func (p *MacroPlayer) Cancel() {
+	p.running = nil
+}
+
+// Apply checks the macro bindings and merges the running macro's current step into the live
+// report. Buttons add to the live ones, overridden axes replace them. Moving an overridden
+// stick by half its travel from where it was when the macro started cancels the macro, so it
+// never fights the pilot.
+func (p *MacroPlayer) Apply(now time.Time, channels map[string]int16, state *GamepadState) {
+	for _, m := range p.macros {
+		if m.bind != nil && m.bind.update(channels[m.bind.channel]) && m.bind.held {
+			p.Trigger(now, m.name)
+		}
+	}
+	if p.running == nil {
+		return
+	}
+	if !p.anchored {
+		p.origin, p.anchored = state.Axes, true
+	}
+
+	// Advance at most one step per update, so every step reaches the gamepad even when an
+	// update comes late. A late step is shortened to keep the macro's overall timing.
+	if step := p.running.steps[p.step]; now.Sub(p.since) >= step.duration {
+		p.step++
+		p.since = p.since.Add(step.duration)
+		if p.step == len(p.running.steps) {
+			p.running = nil
+			return
+		}
+	}
+	step := &p.running.steps[p.step]
+	for axis := range step.axes {
+		if d := int(state.Axes[axis]) - int(p.origin[axis]); d > macroTakeover || d < -macroTakeover {
+			p.running = nil
+			return
+		}
+	}
+	state.Buttons |= step.buttons
+	for axis, v := range step.axes {
+		state.Axes[axis] = v
+	}
+}
@@ -0,0 +1,108 @@
+package helper
+
+import (
+	"testing"
+	"time"
+
+	"github.com/CB2Moon/vgamepad-go/pkg/commons"
+)
+
+var resetSession = MacroConfig{Name: "reset session", Steps: []MacroStep{
+	{Buttons: []string{"back"}, Ms: 100}, {Ms: 100},
+	{Buttons: []string{"a"}, Ms: 100}, {Ms: 500},
+	{Buttons: []string{"a"}, Ms: 100},
+}}
+
+// playMacro triggers the macro and applies it on top of the live report at the given update
+// times in ms, returning the report of each update
+func playMacro(t *testing.T, cfg MacroConfig, live GamepadState, updates []float64) []GamepadState {
+	t.Helper()
+	p := NewMacroPlayer([]MacroConfig{cfg})
+	start := time.Unix(1000, 0)
+	p.Trigger(start, cfg.Name)
+	var out []GamepadState
+	for _, ms := range updates {
+		state := live
+		p.Apply(start.Add(time.Duration(ms*float64(time.Millisecond))), nil, &state)
+		out = append(out, state)
+	}
+	return out
+}
+
+func TestMacroEmitsEveryStepDespiteJitter(t *testing.T) {
+	back, a := commons.XUSB_GAMEPAD_BACK, commons.XUSB_GAMEPAD_A
+	// Updates about every 100 ms, the third one early and the fourth late
+	got := playMacro(t, resetSession, GamepadState{}, []float64{0, 100.1, 199.8, 300.2, 400, 500, 600, 700, 800, 900, 1000})
+	want := []commons.XUSBButton{back, 0, 0, a, 0, 0, 0, 0, a, 0, 0}
+	for i := range want {
+		if got[i].Buttons != want[i] {
+			t.Errorf("update %d: buttons %v, want %v", i, got[i].Buttons, want[i])
+		}
+	}
+}
+
+func TestMacroStepsAtFrameRate(t *testing.T) {
+	var updates []float64
+	for ms := 0.0; ms <= 1000; ms += 10 {
+		updates = append(updates, ms)
+	}
+	got := playMacro(t, resetSession, GamepadState{}, updates)
+	var presses []float64
+	var prev commons.XUSBButton
+	for i, state := range got {
+		b := state.Buttons
+		if b != 0 && b != prev {
+			presses = append(presses, updates[i])
+		}
+		prev = b
+	}
+	if want := []float64{0, 200, 800}; len(presses) != len(want) || presses[0] != want[0] || presses[1] != want[1] || presses[2] != want[2] {
+		t.Errorf("presses at %v ms, want %v", presses, want)
+	}
+}
+
+func TestMacroTakeoverCancels(t *testing.T) {
+	cfg := MacroConfig{Name: "down", Steps: []MacroStep{{Axes: map[string]int16{"left_y": -32768}, Ms: 300}}}
+	p := NewMacroPlayer([]MacroConfig{cfg})
+	now := time.Unix(1000, 0)
+	p.Trigger(now, "down")
+
+	var state GamepadState
+	p.Apply(now, nil, &state)
+	if state.Axes[AxisLeftY] != -32768 {
+		t.Fatalf("axis not overridden: %d", state.Axes[AxisLeftY])
+	}
+	state = GamepadState{}
+	state.Axes[AxisLeftY] = 20000
+	p.Apply(now.Add(10*time.Millisecond), nil, &state)
+	if p.Running() != "" || state.Axes[AxisLeftY] != 20000 {
+		t.Errorf("pilot input did not cancel the macro: running %q, axis %d", p.Running(), state.Axes[AxisLeftY])
+	}
+}
+
+func TestMacroTakeoverFromStartingPosition(t *testing.T) {
+	up := MacroConfig{Name: "up", Steps: []MacroStep{{Axes: map[string]int16{"left_y": 32767}, Ms: 300}}}
+	var held GamepadState
+	held.Axes[AxisLeftY] = -32768
+
+	// A stick held at full deflection when the macro starts does not cancel it
+	got := playMacro(t, up, held, []float64{0, 100, 200})
+	for i, state := range got {
+		if state.Axes[AxisLeftY] != 32767 {
+			t.Errorf("update %d: axis %d, want the macro's 32767", i, state.Axes[AxisLeftY])
+		}
+	}
+
+	// Moving it back by more than half the travel does
+	p := NewMacroPlayer([]MacroConfig{up})
+	now := time.Unix(1000, 0)
+	p.Trigger(now, "up")
+	state := held
+	p.Apply(now, nil, &state)
+	state = GamepadState{}
+	state.Axes[AxisLeftY] = -10000
+	p.Apply(now.Add(10*time.Millisecond), nil, &state)
+	if p.Running() != "" || state.Axes[AxisLeftY] != -10000 {
+		t.Errorf("pilot input did not cancel the macro: running %q, axis %d", p.Running(), state.Axes[AxisLeftY])
+	}
+}
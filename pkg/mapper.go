@@ -0,0 +1,476 @@
+package helper
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/CB2Moon/vgamepad-go/pkg/commons"
+)
+
+// outputKind tells which part of the gamepad report a route writes
+type outputKind int
+
+const (
+	outputAxis outputKind = iota
+	outputTrigger
+	outputButton
+)
+
+// Axis and trigger indices into GamepadState
+const (
+	AxisLeftX = iota
+	AxisLeftY
+	AxisRightX
+	AxisRightY
+	axisCount
+)
+
+const (
+	TriggerLeft = iota
+	TriggerRight
+	triggerCount
+)
+
+// defaultButtonThreshold is the channel value above which a button route presses its button
+const defaultButtonThreshold = 16384
+
+type output struct {
+	kind   outputKind
+	index  int
+	button commons.XUSBButton
+}
+
+var axisOutputs = map[string]int{
+	"left_x":  AxisLeftX,
+	"left_y":  AxisLeftY,
+	"right_x": AxisRightX,
+	"right_y": AxisRightY,
+}
+
+var triggerOutputs = map[string]int{
+	"left_trigger":  TriggerLeft,
+	"right_trigger": TriggerRight,
+}
+
+// ButtonNames maps button output names to X360 buttons
+var ButtonNames = map[string]commons.XUSBButton{
+	"a":              commons.XUSB_GAMEPAD_A,
+	"b":              commons.XUSB_GAMEPAD_B,
+	"x":              commons.XUSB_GAMEPAD_X,
+	"y":              commons.XUSB_GAMEPAD_Y,
+	"start":          commons.XUSB_GAMEPAD_START,
+	"back":           commons.XUSB_GAMEPAD_BACK,
+	"guide":          commons.XUSB_GAMEPAD_GUIDE,
+	"left_shoulder":  commons.XUSB_GAMEPAD_LEFT_SHOULDER,
+	"right_shoulder": commons.XUSB_GAMEPAD_RIGHT_SHOULDER,
+	"left_thumb":     commons.XUSB_GAMEPAD_LEFT_THUMB,
+	"right_thumb":    commons.XUSB_GAMEPAD_RIGHT_THUMB,
+	"dpad_up":        commons.XUSB_GAMEPAD_DPAD_UP,
+	"dpad_down":      commons.XUSB_GAMEPAD_DPAD_DOWN,
+	"dpad_left":      commons.XUSB_GAMEPAD_DPAD_LEFT,
+	"dpad_right":     commons.XUSB_GAMEPAD_DPAD_RIGHT,
+}
+
+// ParseButton looks up a button by its output name
+func ParseButton(name string) (commons.XUSBButton, error) {
+	if b, ok := ButtonNames[strings.ToLower(name)]; ok {
+		return b, nil
+	}
+	return 0, fmt.Errorf("unknown button %q", name)
+}
+
+// parseOutput looks up a gamepad output by name
+func parseOutput(name string) (output, error) {
+	name = strings.ToLower(name)
+	if idx, ok := axisOutputs[name]; ok {
+		return output{kind: outputAxis, index: idx}, nil
+	}
+	if idx, ok := triggerOutputs[name]; ok {
+		return output{kind: outputTrigger, index: idx}, nil
+	}
+	if b, ok := ButtonNames[name]; ok {
+		return output{kind: outputButton, button: b}, nil
+	}
+
+	names := make([]string, 0, len(axisOutputs)+len(triggerOutputs)+len(ButtonNames))
+	for _, m := range []map[string]int{axisOutputs, triggerOutputs} {
+		for n := range m {
+			names = append(names, n)
+		}
+	}
+	for n := range ButtonNames {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return output{}, fmt.Errorf("unknown output %q (want one of %s)", name, strings.Join(names, ", "))
+}
+
+// GamepadState is a complete virtual X360 gamepad report
+type GamepadState struct {
+	Axes     [axisCount]int16
+	Triggers [triggerCount]uint8
+	Buttons  commons.XUSBButton
+}
+
+// Pressed reports whether a button is held
+func (s *GamepadState) Pressed(b commons.XUSBButton) bool {
+	return s.Buttons&b != 0
+}
+
+// AllButtons lists every X360 button in report bit order
+var AllButtons = []commons.XUSBButton{
+	commons.XUSB_GAMEPAD_DPAD_UP, commons.XUSB_GAMEPAD_DPAD_DOWN, commons.XUSB_GAMEPAD_DPAD_LEFT, commons.XUSB_GAMEPAD_DPAD_RIGHT,
+	commons.XUSB_GAMEPAD_START, commons.XUSB_GAMEPAD_BACK, commons.XUSB_GAMEPAD_LEFT_THUMB, commons.XUSB_GAMEPAD_RIGHT_THUMB,
+	commons.XUSB_GAMEPAD_LEFT_SHOULDER, commons.XUSB_GAMEPAD_RIGHT_SHOULDER, commons.XUSB_GAMEPAD_GUIDE,
+	commons.XUSB_GAMEPAD_A, commons.XUSB_GAMEPAD_B, commons.XUSB_GAMEPAD_X, commons.XUSB_GAMEPAD_Y,
+}
+
+// triggerRange selects which travel of a channel drives a trigger
+type triggerRange int
+
+const (
+	rangeFull triggerRange = iota
+	rangePositive
+	rangeNegative
+)
+
+// parseTriggerRange parses a route's range setting
+func parseTriggerRange(name string) (triggerRange, error) {
+	switch strings.ToLower(name) {
+	case "", "full":
+		return rangeFull, nil
+	case "positive":
+		return rangePositive, nil
+	case "negative":
+		return rangeNegative, nil
+	}
+	return 0, fmt.Errorf("unknown range %q (want full, positive or negative)", name)
+}
+
+type compiledRoute struct {
+	channel   string
+	out       output
+	invert    bool
+	threshold int16
+	rng       triggerRange
+}
+
+// layer is a compiled Mapping
+type layer struct {
+	routes []compiledRoute
+	rules  []*buttonRule
+	shift  *shiftLayer
+}
+
+// compileLayer compiles a validated mapping
+func compileLayer(mp Mapping) *layer {
+	l := &layer{}
+	for _, r := range mp.Routes {
+		out, _ := parseOutput(r.Output)
+		threshold := r.Threshold
+		if threshold == 0 {
+			threshold = defaultButtonThreshold
+		}
+		rng, _ := parseTriggerRange(r.Range)
+		l.routes = append(l.routes, compiledRoute{channel: r.Channel, out: out, invert: r.Invert, threshold: threshold, rng: rng})
+	}
+	rules := mp.Buttons
+	if mp.Dpad != nil {
+		rules = append(slices.Clip(rules), mp.Dpad.Rules()...)
+	}
+	for _, r := range rules {
+		l.rules = append(l.rules, newButtonRule(r))
+	}
+	if mp.Shift != nil {
+		l.shift = &shiftLayer{channel: mp.Shift.Channel, enter: mp.Shift.Enter}
+		for _, r := range mp.Shift.rules() {
+			l.shift.rules = append(l.shift.rules, newButtonRule(r))
+		}
+	}
+	return l
+}
+
+// Mapper turns decoded channel values into a gamepad report according to a profile.
+// Button rules keep state between calls, so Map must only be called from one goroutine.
+type Mapper struct {
+	profile    *Profile
+	flight     *layer
+	menu       *layer
+	menuToggle *flickDetector
+	menuMode   atomic.Bool
+	gestures   *GestureRecognizer
+	macros     *MacroPlayer
+	trimmer    *Trimmer
+	rates      *rateSelector
+	filters    *FilterBank
+	slew       map[string]*slewLimiter
+	curves     map[string]*Curve
+}
+
+// NewMapper compiles the mappings and curves of a profile
+func NewMapper(p *Profile) (*Mapper, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+
+	m := &Mapper{profile: p, curves: make(map[string]*Curve)}
+	for name, cfg := range p.Curves {
+		c, err := NewCurve(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("curve %s: %w", name, err)
+		}
+		m.curves[name] = c
+	}
+	m.gestures = NewGestureRecognizer(p.Gestures)
+	m.macros = NewMacroPlayer(p.Macros)
+	m.trimmer = NewTrimmer(p.Trims, p.TrimControls)
+	m.filters = NewFilterBank(p.Filters)
+	m.slew = make(map[string]*slewLimiter, len(p.SlewLimits))
+	for name, l := range p.SlewLimits {
+		m.slew[name] = newSlewLimiter(l)
+	}
+	if p.RateSets != nil {
+		m.rates = newRateSelector(*p.RateSets)
+	}
+	m.flight = compileLayer(p.Mapping)
+	if p.Menu != nil {
+		m.menu = compileLayer(p.Menu.Mapping)
+		m.menuToggle = newFlickDetector(p.Menu.Toggle)
+	}
+	return m, nil
+}
+
+// Profile returns the profile the mapper was built from
+func (m *Mapper) Profile() *Profile {
+	return m.profile
+}
+
+// Curves returns the compiled response curves by channel
+func (m *Mapper) Curves() map[string]*Curve {
+	return m.curves
+}
+
+// Filter smooths one channel sample taken at time t with the profile's filter for it. It is
+// called for every RC frame, from the goroutine reading them; name is the channel in Mode 2
+// layout.
+func (m *Mapper) Filter(t time.Time, name string, v int16) int16 {
+	return m.filters.Apply(t, name, v)
+}
+
+// Trim adjusts the trims with the profile's trim controls and offsets the channel values by
+// them in place. It returns the axes whose trim changed.
+func (m *Mapper) Trim(now time.Time, channels map[string]int16) []string {
+	return m.trimmer.Apply(now, channels)
+}
+
+// Trims returns the current trims, including live adjustments not yet in Profile
+func (m *Mapper) Trims() map[string]int16 {
+	return m.trimmer.Trims()
+}
+
+// Shape runs channel values through their response curves in place, then scales them by the
+// active rate set. The rate set is selected from the values before the curves.
+func (m *Mapper) Shape(channels map[string]int16) {
+	if m.rates != nil {
+		m.rates.selectSet(channels)
+	}
+	for name, c := range m.curves {
+		if v, ok := channels[name]; ok {
+			channels[name] = c.Apply(v)
+		}
+	}
+	if m.rates != nil {
+		m.rates.scale(channels)
+	}
+}
+
+// Limit applies the profile's slew limits to the shaped channel values in place
+func (m *Mapper) Limit(now time.Time, channels map[string]int16) {
+	for name, s := range m.slew {
+		if v, ok := channels[name]; ok {
+			channels[name] = s.limit(now, v)
+		}
+	}
+}
+
+// RateSet returns the name of the active rate set, or "" if the profile has none
+func (m *Mapper) RateSet() string {
+	if m.rates == nil {
+		return ""
+	}
+	return m.rates.name()
+}
+
+// HasMenu reports whether the profile has a menu mapping
+func (m *Mapper) HasMenu() bool {
+	return m.menu != nil
+}
+
+// MenuMode reports whether the menu mapping is active instead of the flight mapping
+func (m *Mapper) MenuMode() bool {
+	return m.menuMode.Load()
+}
+
+// SetMenuMode switches between the flight and menu mapping
+func (m *Mapper) SetMenuMode(on bool) {
+	m.menuMode.Store(on && m.menu != nil)
+}
+
+// Recognize runs the profile's gestures on the decoded channel values and returns the ones
+// just recognized. Menu toggles and macros take effect immediately, button presses are pulsed
+// by the following calls to Map and the remaining actions are up to the caller.
+func (m *Mapper) Recognize(now time.Time, channels map[string]int16) []GestureConfig {
+	recognized := m.gestures.Update(now, channels)
+	for _, g := range recognized {
+		if g.Action.ToggleMenu {
+			m.toggleMenu()
+		}
+		if g.Action.Macro != "" {
+			m.macros.Trigger(now, g.Action.Macro)
+		}
+	}
+	return recognized
+}
+
+// toggleMenu switches between the flight and menu mapping, starting the newly active one
+// from released buttons
+func (m *Mapper) toggleMenu() {
+	m.SetMenuMode(!m.MenuMode())
+	m.active().reset()
+}
+
+// Map computes the gamepad report for the given channel values at time now.
+// Several routes to the same axis add up, triggers take the largest value and buttons are combined.
+func (m *Mapper) Map(now time.Time, channels map[string]int16) GamepadState {
+	if m.menuToggle != nil && m.menuToggle.update(now, channels[m.menuToggle.channel]) {
+		m.toggleMenu()
+	}
+	state := m.active().apply(now, channels)
+	state.Buttons |= m.gestures.Buttons(now)
+	m.macros.Apply(now, channels, &state)
+	return state
+}
+
+// Macro returns the name of the running macro, or "" if none is running
+func (m *Mapper) Macro() string {
+	return m.macros.Running()
+}
+
+// ResetGestures drops gestures in progress; held conditions must be let go of before they are
+// recognized again
+func (m *Mapper) ResetGestures() {
+	m.gestures.Reset()
+}
+
+// CancelMacro stops the running macro
+func (m *Mapper) CancelMacro() {
+	m.macros.Cancel()
+}
+
+// active returns the mapping currently in use
+func (m *Mapper) active() *layer {
+	if m.MenuMode() {
+		return m.menu
+	}
+	return m.flight
+}
+
+// apply computes the layer's gamepad report
+func (l *layer) apply(now time.Time, channels map[string]int16) GamepadState {
+	var state GamepadState
+	var axes [axisCount]int32
+
+	rules, shifted := l.rules, false
+	if l.shift != nil {
+		if l.shift.update(channels[l.shift.channel]) {
+			// Start the newly active button mappings from released buttons
+			resetRules(l.rules)
+			resetRules(l.shift.rules)
+		}
+		if l.shift.held {
+			rules, shifted = l.shift.rules, true
+		}
+	}
+
+	for _, r := range l.routes {
+		v, ok := channels[r.channel]
+		if !ok {
+			continue
+		}
+		if r.invert {
+			v = InvertAxis(v)
+		}
+
+		switch r.out.kind {
+		case outputAxis:
+			axes[r.out.index] += int32(v)
+		case outputTrigger:
+			var t uint8
+			switch r.rng {
+			case rangePositive:
+				t = HalfAxisToTrigger(v)
+			case rangeNegative:
+				t = HalfAxisToTrigger(InvertAxis(v))
+			default:
+				t = AxisToTrigger(v)
+			}
+			state.Triggers[r.out.index] = max(state.Triggers[r.out.index], t)
+		case outputButton:
+			if !shifted && v > r.threshold {
+				state.Buttons |= r.out.button
+			}
+		}
+	}
+
+	for _, r := range rules {
+		state.Buttons |= r.update(now, channels[r.channel])
+	}
+
+	for i, v := range axes {
+		state.Axes[i] = ClampAxis(v)
+	}
+	return state
+}
+
+// reset forgets the state of the layer's button rules
+func (l *layer) reset() {
+	resetRules(l.rules)
+	if l.shift != nil {
+		l.shift.held = false
+		resetRules(l.shift.rules)
+	}
+}
+
+func resetRules(rules []*buttonRule) {
+	for _, r := range rules {
+		r.reset()
+	}
+}
+
+// InvertAxis mirrors an axis value around center
+func InvertAxis(v int16) int16 {
+	return ClampAxis(-int32(v))
+}
+
+// ClampAxis limits a value to the X360 stick range
+func ClampAxis(v int32) int16 {
+	return int16(min(max(v, -32768), 32767))
+}
+
+// AxisToTrigger maps the full stick range (-32768 to 32767) onto the trigger range (0 to 255)
+func AxisToTrigger(v int16) uint8 {
+	return uint8((int32(v) + 32768) / 257)
+}
+
+// HalfAxisToTrigger maps the positive half of the stick range (0 to 32767) onto the trigger
+// range (0 to 255); negative values release the trigger
+func HalfAxisToTrigger(v int16) uint8 {
+	if v <= 0 {
+		return 0
+	}
+	return uint8(int32(v) * 255 / 32767)
+}
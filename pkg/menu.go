@@ -0,0 +1,90 @@
+package helper
+
+import (
+	"fmt"
+	"time"
+)
+
+// defaultFlickHold is the longest a channel may stay past the threshold for a flick
+const defaultFlickHold = 400 * time.Millisecond
+
+// MenuConfig is the mapping used while navigating sim menus instead of flying, and the flick
+// that switches between the two
+type MenuConfig struct {
+	Toggle FlickConfig `json:"toggle"`
+	Mapping
+}
+
+// Validate checks the toggle and the menu mapping
+func (c *MenuConfig) Validate() error {
+	if err := c.Toggle.Validate(); err != nil {
+		return fmt.Errorf("toggle: %w", err)
+	}
+	return c.Mapping.Validate()
+}
+
+// FlickConfig describes a quick flick of a channel: past Enter and back to center within
+// MaxHoldMs. Holding the channel longer is not a flick, so the same channel can still drive
+// a held button.
+type FlickConfig struct {
+	Channel string `json:"channel"`
+	// Enter is the deflection the flick has to reach, positive or negative
+	Enter int16 `json:"enter"`
+	// MaxHoldMs is the longest the channel may stay past half of Enter (default 400)
+	MaxHoldMs int `json:"max_hold_ms,omitempty"`
+}
+
+// Validate checks the channel, threshold and timing
+func (c *FlickConfig) Validate() error {
+	if err := validateChannel(c.Channel); err != nil {
+		return err
+	}
+	if c.Enter == 0 {
+		return fmt.Errorf("enter threshold must not be 0")
+	}
+	if c.MaxHoldMs < 0 {
+		return fmt.Errorf("max_hold_ms must not be negative")
+	}
+	return nil
+}
+
+// flickDetector recognizes flicks of one channel
+type flickDetector struct {
+	channel string
+	enter   int16
+	exit    int16
+	maxHold time.Duration
+
+	out     bool // past exit since the time below
+	reached bool // reached enter while out
+	since   time.Time
+}
+
+func newFlickDetector(c FlickConfig) *flickDetector {
+	return &flickDetector{
+		channel: c.Channel,
+		enter:   c.Enter,
+		exit:    c.Enter / 2,
+		maxHold: msOrDefault(c.MaxHoldMs, defaultFlickHold),
+	}
+}
+
+// update feeds the detector the channel's current value and reports a completed flick
+func (f *flickDetector) update(now time.Time, v int16) bool {
+	past := v > f.exit
+	reached := v >= f.enter
+	if f.enter < 0 {
+		past, reached = v < f.exit, v <= f.enter
+	}
+
+	switch {
+	case past && !f.out:
+		f.out, f.reached, f.since = true, reached, now
+	case past:
+		f.reached = f.reached || reached
+	case f.out:
+		f.out = false
+		return f.reached && now.Sub(f.since) <= f.maxHold
+	}
+	return false
+}
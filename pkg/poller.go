@@ -0,0 +1,177 @@
+package helper
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Poll scheduler limits
+const (
+	MaxPollsInFlight = 2
+	minPollInterval  = time.Millisecond
+	maxPollInterval  = 250 * time.Millisecond
+	replyTimeWeight  = 0.125 // EWMA weight of the newest reply time sample
+)
+
+// PollScheduler decides when the next channel request may be sent to the RC.
+// It measures how long the RC takes to reply, keeps up to MaxPollsInFlight requests
+// outstanding and pairs replies with their requests by DUML sequence number.
+// It is safe for concurrent use.
+type PollScheduler struct {
+	mu          sync.Mutex
+	target      time.Duration // interval derived from the target rate, 0 = as fast as the RC replies
+	maxInFlight int
+	interval    time.Duration // current tuned interval between requests
+	replyTime   time.Duration // smoothed reply time
+	lastSent    time.Time
+	inFlight    []pendingPoll
+	wake        chan struct{}
+	sent        uint64
+	received    uint64
+	lost        uint64
+}
+
+type pendingPoll struct {
+	seq  uint16
+	sent time.Time
+}
+
+// NewPollScheduler creates a scheduler for the given target rate in Hz (0 = unlimited)
+// and number of requests kept in flight (clamped to 1..MaxPollsInFlight)
+func NewPollScheduler(targetRate float64, maxInFlight int) *PollScheduler {
+	s := &PollScheduler{
+		maxInFlight: max(1, min(maxInFlight, MaxPollsInFlight)),
+		wake:        make(chan struct{}, 1),
+	}
+	if targetRate > 0 {
+		s.target = time.Duration(float64(time.Second) / targetRate)
+	}
+	s.interval = max(s.target, minPollInterval)
+	return s
+}
+
+// Ready reports whether a new request may be sent now
+func (s *PollScheduler) Ready(now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.inFlight) < s.maxInFlight && now.Sub(s.lastSent) >= s.interval
+}
+
+// Delay returns how long to wait before the next request may be sent
+func (s *PollScheduler) Delay(now time.Time) time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return max(0, s.interval-now.Sub(s.lastSent))
+}
+
+// Wake returns a channel that receives a value whenever a request slot is freed
+func (s *PollScheduler) Wake() <-chan struct{} {
+	return s.wake
+}
+
+// notify signals a waiting sender without blocking; callers hold s.mu
+func (s *PollScheduler) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Sent registers a request written to the RC
+func (s *PollScheduler) Sent(seq uint16, at time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.inFlight = append(s.inFlight, pendingPoll{seq: seq, sent: at})
+	s.lastSent = at
+	s.sent++
+}
+
+// Completed pairs a reply with its request and returns when that request was sent.
+// If no request carries the reply's sequence number the oldest one is assumed,
+// in which case matched is false and the reply time is not used for tuning.
+func (s *PollScheduler) Completed(seq uint16, at time.Time) (sent time.Time, matched bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.inFlight) == 0 {
+		return time.Time{}, false
+	}
+	s.received++
+
+	idx := 0
+	for i, p := range s.inFlight {
+		if p.seq == seq {
+			idx, matched = i, true
+			break
+		}
+	}
+	sent = s.inFlight[idx].sent
+
+	// Requests older than the paired one will never be answered
+	s.lost += uint64(idx)
+	s.inFlight = s.inFlight[idx+1:]
+	s.notify()
+
+	if matched {
+		s.observe(at.Sub(sent))
+	}
+	return sent, matched
+}
+
+// Expire drops requests that have been waiting longer than timeout and returns how many were dropped
+func (s *PollScheduler) Expire(now time.Time, timeout time.Duration) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	dropped := 0
+	for len(s.inFlight) > 0 && now.Sub(s.inFlight[0].sent) > timeout {
+		s.inFlight = s.inFlight[1:]
+		dropped++
+	}
+	if dropped > 0 {
+		s.lost += uint64(dropped)
+		// Back off so a struggling RC is not flooded with requests
+		s.interval = min(s.interval*5/4+minPollInterval, maxPollInterval)
+		s.notify()
+	}
+	return dropped
+}
+
+// observe feeds a reply time sample into the estimate and retunes the interval
+func (s *PollScheduler) observe(rtt time.Duration) {
+	if s.replyTime == 0 {
+		s.replyTime = rtt
+	} else {
+		s.replyTime += time.Duration(replyTimeWeight * float64(rtt-s.replyTime))
+	}
+
+	// With n requests in flight the RC can answer one every replyTime/n
+	s.interval = max(s.target, s.replyTime/time.Duration(s.maxInFlight), minPollInterval)
+	s.interval = min(s.interval, maxPollInterval)
+}
+
+// Reset forgets outstanding requests and measurements
+func (s *PollScheduler) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.inFlight = nil
+	s.replyTime = 0
+	s.interval = max(s.target, minPollInterval)
+	s.lastSent = time.Time{}
+	s.sent, s.received, s.lost = 0, 0, 0
+	s.notify()
+}
+
+// String formats the scheduler state as a one-line summary
+func (s *PollScheduler) String() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rate := 0.0
+	if s.interval > 0 {
+		rate = float64(time.Second) / float64(s.interval)
+	}
+	return fmt.Sprintf("interval=%v (%.0f Hz) reply=%v in-flight=%d/%d sent=%d received=%d lost=%d",
+		s.interval, rate, s.replyTime, len(s.inFlight), s.maxInFlight, s.sent, s.received, s.lost)
+}
@@ -0,0 +1,219 @@
+package helper
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Profile describes how decoded RC channels drive the virtual gamepad
+type Profile struct {
+	Name string `json:"name"`
+	// Path is the file the profile was loaded from, empty for profiles built into the executable
+	Path string `json:"-"`
+	Mapping
+	Curves       map[string]CurveConfig  `json:"curves,omitempty"`        // response curves by channel
+	Menu         *MenuConfig             `json:"menu,omitempty"`          // mapping for navigating sim menus
+	Gestures     []GestureConfig         `json:"gestures,omitempty"`      // stick gestures triggering actions
+	Macros       []MacroConfig           `json:"macros,omitempty"`        // timed button and axis sequences
+	Trims        map[string]int16        `json:"trims,omitempty"`         // offsets added before curves
+	TrimControls []TrimControl           `json:"trim_controls,omitempty"` // controls adjusting trims live
+	RateSets     *RateSetsConfig         `json:"rate_sets,omitempty"`     // selectable stick output scales
+	Filters      map[string]FilterConfig `json:"filters,omitempty"`       // smoothing by channel
+	SlewLimits   map[string]SlewLimit    `json:"slew_limits,omitempty"`   // maximum rate of change by channel
+}
+
+// Mapping is a set of routes and button rules that together produce a gamepad report
+type Mapping struct {
+	Routes  []Route      `json:"routes,omitempty"`
+	Buttons []ButtonRule `json:"buttons,omitempty"` // axis-to-button rules
+	Dpad    *DpadConfig  `json:"dpad,omitempty"`    // channels driving the D-pad
+	Shift   *ShiftConfig `json:"shift,omitempty"`   // button rules used while a modifier is held
+}
+
+// Route connects one RC channel to one gamepad output.
+// Outputs are stick axes (left_x, left_y, right_x, right_y), triggers (left_trigger, right_trigger)
+// or buttons (a, b, x, y, start, back, guide, left_shoulder, right_shoulder, left_thumb,
+// right_thumb, dpad_up, dpad_down, dpad_left, dpad_right).
+type Route struct {
+	Channel string `json:"channel"`
+	Output  string `json:"output"`
+	Invert  bool   `json:"invert,omitempty"`
+	// Threshold is the channel value above which a button output is pressed (default 16384)
+	Threshold int16 `json:"threshold,omitempty"`
+	// Range selects which travel drives a trigger output: full (default, minimum to maximum),
+	// positive (center to maximum) or negative (center to minimum)
+	Range string `json:"range,omitempty"`
+}
+
+// ParseProfile decodes and validates a JSON profile
+func ParseProfile(data []byte) (*Profile, error) {
+	var p Profile
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("invalid profile JSON: %w", err)
+	}
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// LoadProfile reads a JSON profile from disk. A profile without a name is named after its file.
+func LoadProfile(path string) (*Profile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	p, err := ParseProfile(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+	if p.Name == "" {
+		p.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	p.Path = path
+	return p, nil
+}
+
+// LoadProfiles reads all *.json profiles in dir. A missing directory yields no profiles;
+// profiles that fail to load are reported in errs and skipped.
+func LoadProfiles(dir string) (profiles []*Profile, errs []error) {
+	paths, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	sort.Strings(paths)
+	for _, path := range paths {
+		p, err := LoadProfile(path)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		profiles = append(profiles, p)
+	}
+	return profiles, errs
+}
+
+// Save writes the profile as indented JSON
+func (p *Profile) Save(path string) error {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// Validate checks that all mappings and curves name known channels and outputs
+func (p *Profile) Validate() error {
+	if err := p.Mapping.Validate(); err != nil {
+		return err
+	}
+	for name, c := range p.Curves {
+		if err := validateChannel(name); err != nil {
+			return fmt.Errorf("curve: %w", err)
+		}
+		if err := c.Validate(); err != nil {
+			return fmt.Errorf("curve %s: %w", name, err)
+		}
+	}
+	if p.Menu != nil {
+		if err := p.Menu.Validate(); err != nil {
+			return fmt.Errorf("menu: %w", err)
+		}
+	}
+	for i := range p.Gestures {
+		if err := p.Gestures[i].Validate(); err != nil {
+			return fmt.Errorf("gesture %d: %w", i+1, err)
+		}
+		if p.Gestures[i].Action.ToggleMenu && p.Menu == nil {
+			return fmt.Errorf("gesture %d: toggle_menu needs a menu mapping", i+1)
+		}
+		if name := p.Gestures[i].Action.Macro; name != "" && !slices.ContainsFunc(p.Macros, func(m MacroConfig) bool { return m.Name == name }) {
+			return fmt.Errorf("gesture %d: unknown macro %q", i+1, name)
+		}
+	}
+	for name, f := range p.Filters {
+		if err := validateChannel(name); err != nil {
+			return fmt.Errorf("filter: %w", err)
+		}
+		if err := f.Validate(); err != nil {
+			return fmt.Errorf("filter %s: %w", name, err)
+		}
+	}
+	for name, l := range p.SlewLimits {
+		if err := validateChannel(name); err != nil {
+			return fmt.Errorf("slew limit: %w", err)
+		}
+		if err := l.Validate(); err != nil {
+			return fmt.Errorf("slew limit %s: %w", name, err)
+		}
+	}
+	if err := validateTrims(p.Trims); err != nil {
+		return fmt.Errorf("trims: %w", err)
+	}
+	for i := range p.TrimControls {
+		if err := p.TrimControls[i].Validate(); err != nil {
+			return fmt.Errorf("trim control %d: %w", i+1, err)
+		}
+	}
+	if p.RateSets != nil {
+		if err := p.RateSets.Validate(); err != nil {
+			return fmt.Errorf("rate sets: %w", err)
+		}
+	}
+	names := make(map[string]bool, len(p.Macros))
+	for i := range p.Macros {
+		if err := p.Macros[i].Validate(); err != nil {
+			return fmt.Errorf("macro %d: %w", i+1, err)
+		}
+		if names[p.Macros[i].Name] {
+			return fmt.Errorf("macro %d: duplicate name %q", i+1, p.Macros[i].Name)
+		}
+		names[p.Macros[i].Name] = true
+	}
+	return nil
+}
+
+// Validate checks that all routes and button rules name known channels and outputs
+func (m *Mapping) Validate() error {
+	for i, r := range m.Routes {
+		if err := validateChannel(r.Channel); err != nil {
+			return fmt.Errorf("route %d: %w", i+1, err)
+		}
+		out, err := parseOutput(r.Output)
+		if err != nil {
+			return fmt.Errorf("route %d: %w", i+1, err)
+		}
+		if _, err := parseTriggerRange(r.Range); err != nil {
+			return fmt.Errorf("route %d: %w", i+1, err)
+		}
+		if r.Range != "" && out.kind != outputTrigger {
+			return fmt.Errorf("route %d: range only applies to trigger outputs", i+1)
+		}
+	}
+	for i, b := range m.Buttons {
+		if err := b.Validate(); err != nil {
+			return fmt.Errorf("button rule %d: %w", i+1, err)
+		}
+	}
+	if m.Dpad != nil {
+		if err := m.Dpad.Validate(); err != nil {
+			return fmt.Errorf("dpad: %w", err)
+		}
+	}
+	if m.Shift != nil {
+		if err := m.Shift.Validate(); err != nil {
+			return fmt.Errorf("shift: %w", err)
+		}
+	}
+	return nil
+}
+
+// validateChannel checks that a channel name is decoded by the translator
+func validateChannel(name string) error {
+	if !slices.Contains(KnownChannels, name) {
+		return fmt.Errorf("unknown channel %q (want one of %s)", name, strings.Join(KnownChannels, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,289 @@
+package helper
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Rate models as named by Betaflight's rates_type setting
+const (
+	RatesBetaflight = "betaflight"
+	RatesActual     = "actual"
+	RatesKiss       = "kiss"
+)
+
+// RatesConfig describes one axis in the units of the Betaflight CLI.
+// For the betaflight and kiss models rc_rate and super_rate are percentages (100 = 1.00)
+// and for the actual model they are the center sensitivity and max rate in tens of deg/s.
+type RatesConfig struct {
+	Model     string `json:"model"`
+	RcRate    int    `json:"rc_rate"`
+	Expo      int    `json:"expo,omitempty"`
+	SuperRate int    `json:"super_rate,omitempty"`
+	// MaxRate is the full-deflection rate in deg/s configured in the simulator. The curve's
+	// deg/s output is divided by it, so sim and quad feel the same. 0 scales the curve's own
+	// maximum to full deflection.
+	MaxRate float64 `json:"max_rate,omitempty"`
+}
+
+// Validate checks the model name and value ranges
+func (r *RatesConfig) Validate() error {
+	switch strings.ToLower(r.Model) {
+	case RatesBetaflight, RatesActual, RatesKiss:
+	default:
+		return fmt.Errorf("unknown rates model %q (want betaflight, actual or kiss)", r.Model)
+	}
+	switch {
+	case r.RcRate <= 0 || r.RcRate > 255:
+		return fmt.Errorf("rc_rate %d out of range 1 to 255", r.RcRate)
+	case r.Expo < 0 || r.Expo > 100:
+		return fmt.Errorf("expo %d out of range 0 to 100", r.Expo)
+	case r.SuperRate < 0 || r.SuperRate > 255:
+		return fmt.Errorf("super_rate %d out of range 0 to 255", r.SuperRate)
+	case r.MaxRate < 0:
+		return fmt.Errorf("max_rate %v must not be negative", r.MaxRate)
+	}
+	return nil
+}
+
+// setpointRateLimit is Betaflight's SETPOINT_RATE_LIMIT, the largest rate in deg/s any rate
+// model may command
+const setpointRateLimit = 1998
+
+// DegreesPerSecond computes the rotation rate a flight controller would command for a
+// stick position from -1 to 1, following Betaflight's rate implementations
+func (r *RatesConfig) DegreesPerSecond(x float64) float64 {
+	rate := r.unlimitedRate(x)
+	return math.Max(-setpointRateLimit, math.Min(setpointRateLimit, rate))
+}
+
+// unlimitedRate is the rate of the configured model before Betaflight's setpoint limit
+func (r *RatesConfig) unlimitedRate(x float64) float64 {
+	x = math.Max(-1, math.Min(1, x))
+	a := math.Abs(x)
+	rcRate := float64(r.RcRate) / 100
+	expo := float64(r.Expo) / 100
+	superRate := float64(r.SuperRate) / 100
+
+	switch strings.ToLower(r.Model) {
+	case RatesActual:
+		expoed := a * (math.Pow(x, 5)*expo + x*(1-expo))
+		center := float64(r.RcRate) * 10
+		movement := math.Max(0, float64(r.SuperRate)*10-center)
+		return x*center + movement*expoed
+
+	case RatesKiss:
+		factor := 1 / math.Max(0.01, math.Min(1, 1-a*superRate))
+		command := (x*x*x*expo + x*(1-expo)) * (rcRate / 10)
+		return 2000 * factor * command
+
+	default:
+		if rcRate > 2 {
+			rcRate += 14.54 * (rcRate - 2)
+		}
+		if expo > 0 {
+			x = x*a*a*a*expo + x*(1-expo)
+		}
+		rate := 200 * rcRate * x
+		if superRate > 0 {
+			rate *= 1 / math.Max(0.01, math.Min(1, 1-a*superRate))
+		}
+		return rate
+	}
+}
+
+// Eval maps a stick position to the normalized output sent to the simulator
+func (r *RatesConfig) Eval(x float64) float64 {
+	full := r.MaxRate
+	if full == 0 {
+		full = math.Abs(r.DegreesPerSecond(1))
+	}
+	if full == 0 {
+		return 0
+	}
+	return r.DegreesPerSecond(x) / full
+}
+
+// ThrottleConfig is Betaflight's throttle curve (thr_mid and thr_expo, both 0 to 100)
+type ThrottleConfig struct {
+	Mid  int `json:"mid"`
+	Expo int `json:"expo"`
+}
+
+// Validate checks the value ranges
+func (t *ThrottleConfig) Validate() error {
+	if t.Mid < 0 || t.Mid > 100 || t.Expo < 0 || t.Expo > 100 {
+		return fmt.Errorf("throttle mid %d and expo %d must be within 0 to 100", t.Mid, t.Expo)
+	}
+	return nil
+}
+
+// Eval applies the throttle curve to a stick position from -1 (idle) to 1 (full)
+func (t *ThrottleConfig) Eval(x float64) float64 {
+	pos := (math.Max(-1, math.Min(1, x)) + 1) / 2
+	mid := float64(t.Mid) / 100
+	expo := float64(t.Expo) / 100
+
+	d := pos - mid
+	span := mid
+	if d > 0 {
+		span = 1 - mid
+	}
+	out := pos
+	if span > 0 {
+		out = mid + d*(1-expo+expo*d*d/(span*span))
+	}
+	return out*2 - 1
+}
+
+// BetaflightRates holds rate settings imported from Betaflight CLI output
+type BetaflightRates struct {
+	Axes     map[string]RatesConfig // by axis: roll, pitch, yaw
+	Throttle *ThrottleConfig
+}
+
+// selectedRateProfile collects the "set" lines of the rate profile selected last by a
+// "rateprofile N" line, on top of those before the first such line
+func selectedRateProfile(text string) (map[string]string, error) {
+	const global = -1
+	blocks := map[int]map[string]string{global: {}}
+	current := global
+
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if rest, ok := strings.CutPrefix(line, "rateprofile "); ok {
+			n, err := strconv.Atoi(strings.TrimSpace(rest))
+			if err != nil {
+				return nil, fmt.Errorf("invalid rate profile line %q", line)
+			}
+			current = n
+			if blocks[n] == nil {
+				blocks[n] = map[string]string{}
+			}
+			continue
+		}
+		rest, ok := strings.CutPrefix(line, "set ")
+		if !ok {
+			continue
+		}
+		name, raw, ok := strings.Cut(rest, "=")
+		if !ok {
+			continue
+		}
+		blocks[current][strings.ToLower(strings.TrimSpace(name))] = strings.TrimSpace(raw)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	settings := blocks[global]
+	for name, raw := range blocks[current] {
+		settings[name] = raw
+	}
+	return settings, nil
+}
+
+// ParseBetaflightRates reads "set name = value" lines as printed by Betaflight's "dump" or
+// "diff" CLI commands. Unrelated lines are ignored. Settings missing from the text use
+// Betaflight's defaults; like "diff" in Betaflight 4.3 and later, no rates_type means actual.
+// Output of "dump all" or "diff all" holds a block per rate profile; only the one selected
+// last, which those commands restore at the end, is imported.
+func ParseBetaflightRates(text string) (*BetaflightRates, error) {
+	settings, err := selectedRateProfile(text)
+	if err != nil {
+		return nil, err
+	}
+
+	model := RatesActual
+	values := map[string]int{}
+	var throttleMid, throttleExpo *int
+	for name, raw := range settings {
+		if name == "rates_type" {
+			model = strings.ToLower(raw)
+			continue
+		}
+		v, err := strconv.Atoi(raw)
+		if err != nil {
+			continue
+		}
+		switch name {
+		case "thr_mid":
+			throttleMid = &v
+		case "thr_expo":
+			throttleExpo = &v
+		default:
+			values[name] = v
+		}
+	}
+
+	rates := &BetaflightRates{Axes: make(map[string]RatesConfig)}
+	found := false
+	for _, axis := range []string{"roll", "pitch", "yaw"} {
+		cfg := RatesConfig{Model: model, RcRate: 100, Expo: 0, SuperRate: 70}
+		if model == RatesActual {
+			cfg.RcRate, cfg.SuperRate = 7, 67
+		}
+		if v, ok := values[axis+"_rc_rate"]; ok {
+			cfg.RcRate, found = v, true
+		}
+		if v, ok := values[axis+"_expo"]; ok {
+			cfg.Expo, found = v, true
+		}
+		if v, ok := values[axis+"_srate"]; ok {
+			cfg.SuperRate, found = v, true
+		}
+		if err := cfg.Validate(); err != nil {
+			return nil, fmt.Errorf("%s: %w", axis, err)
+		}
+		rates.Axes[axis] = cfg
+	}
+
+	if throttleMid != nil || throttleExpo != nil {
+		t := &ThrottleConfig{Mid: 50}
+		if throttleMid != nil {
+			t.Mid = *throttleMid
+		}
+		if throttleExpo != nil {
+			t.Expo = *throttleExpo
+		}
+		if err := t.Validate(); err != nil {
+			return nil, err
+		}
+		rates.Throttle = t
+		found = true
+	}
+
+	if !found {
+		return nil, fmt.Errorf("no rate settings found, paste the \"set ..._rc_rate = ...\" lines from the Betaflight CLI")
+	}
+	return rates, nil
+}
+
+// DefaultAxisChannels assigns flight axes to channels in Mode 2
+var DefaultAxisChannels = StickMode2.AxisChannels()
+
+// ApplyTo stores the rates in the profile's curves of the channels the axes are on,
+// keeping their deadzones
+func (b *BetaflightRates) ApplyTo(p *Profile, axisChannels map[string]string) {
+	if p.Curves == nil {
+		p.Curves = make(map[string]CurveConfig)
+	}
+	for axis, cfg := range b.Axes {
+		channel := axisChannels[axis]
+		curve := p.Curves[channel]
+		curve.Rates = &cfg
+		curve.Throttle = nil
+		p.Curves[channel] = curve
+	}
+	if b.Throttle != nil {
+		channel := axisChannels["throttle"]
+		curve := p.Curves[channel]
+		curve.Throttle = b.Throttle
+		curve.Rates = nil
+		p.Curves[channel] = curve
+	}
+}
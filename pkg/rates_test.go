@@ -0,0 +1,159 @@
+package helper
+
+import (
+	"math"
+	"testing"
+)
+
+func TestRatesDegreesPerSecond(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  RatesConfig
+		x    float64
+		want float64
+	}{
+		{"betaflight full", RatesConfig{Model: RatesBetaflight, RcRate: 100, SuperRate: 70}, 1, 666.667},
+		{"betaflight half", RatesConfig{Model: RatesBetaflight, RcRate: 100, SuperRate: 70}, 0.5, 153.846},
+		{"betaflight negative", RatesConfig{Model: RatesBetaflight, RcRate: 100, SuperRate: 70}, -1, -666.667},
+		{"betaflight no super", RatesConfig{Model: RatesBetaflight, RcRate: 100}, 1, 200},
+		{"betaflight high rc_rate", RatesConfig{Model: RatesBetaflight, RcRate: 255}, 0.9, 1898.46},
+		{"betaflight setpoint limit", RatesConfig{Model: RatesBetaflight, RcRate: 255}, 1, 1998},
+		{"betaflight expo", RatesConfig{Model: RatesBetaflight, RcRate: 100, Expo: 50}, 0.5, 56.25},
+		{"actual full", RatesConfig{Model: RatesActual, RcRate: 7, SuperRate: 67}, 1, 670},
+		{"actual half", RatesConfig{Model: RatesActual, RcRate: 7, SuperRate: 67}, 0.5, 185},
+		{"actual center slope", RatesConfig{Model: RatesActual, RcRate: 7, SuperRate: 67}, 0.01, 0.76},
+		{"kiss full", RatesConfig{Model: RatesKiss, RcRate: 100, SuperRate: 70}, 1, 666.667},
+		{"kiss half", RatesConfig{Model: RatesKiss, RcRate: 100, SuperRate: 70}, 0.5, 153.846},
+		// 4000 deg/s unlimited
+		{"kiss setpoint limit", RatesConfig{Model: RatesKiss, RcRate: 200, SuperRate: 90}, 1, 1998},
+		{"kiss setpoint limit negative", RatesConfig{Model: RatesKiss, RcRate: 200, SuperRate: 90}, -1, -1998},
+		{"actual setpoint limit", RatesConfig{Model: RatesActual, RcRate: 20, SuperRate: 255}, 1, 1998},
+		{"clamped beyond full", RatesConfig{Model: RatesActual, RcRate: 7, SuperRate: 67}, 1.5, 670},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.cfg.DegreesPerSecond(tt.x); math.Abs(got-tt.want) > 0.01 {
+				t.Errorf("DegreesPerSecond(%g) = %.3f, want %.3f", tt.x, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRatesEval(t *testing.T) {
+	cfg := RatesConfig{Model: RatesActual, RcRate: 7, SuperRate: 67}
+	if got := cfg.Eval(1); got != 1 {
+		t.Errorf("without max_rate full stick = %g, want 1", got)
+	}
+	cfg.MaxRate = 1000
+	if got := cfg.Eval(1); math.Abs(got-0.67) > 1e-9 {
+		t.Errorf("with max_rate 1000 full stick = %g, want 0.67", got)
+	}
+	if got := cfg.Eval(-0.5); math.Abs(got+0.185) > 1e-9 {
+		t.Errorf("with max_rate 1000 half stick = %g, want -0.185", got)
+	}
+}
+
+func TestThrottleEval(t *testing.T) {
+	tests := []struct {
+		cfg     ThrottleConfig
+		x, want float64
+	}{
+		{ThrottleConfig{Mid: 50}, 0.3, 0.3},
+		{ThrottleConfig{Mid: 50, Expo: 100}, -1, -1},
+		{ThrottleConfig{Mid: 50, Expo: 100}, 0, 0},
+		{ThrottleConfig{Mid: 50, Expo: 100}, 1, 1},
+		// Full expo flattens the curve around mid: three quarters throttle gives 56.25%
+		{ThrottleConfig{Mid: 50, Expo: 100}, 0.5, 0.125},
+		{ThrottleConfig{Mid: 25}, -0.5, -0.5},
+	}
+	for _, tt := range tests {
+		if got := tt.cfg.Eval(tt.x); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("%+v Eval(%g) = %g, want %g", tt.cfg, tt.x, got, tt.want)
+		}
+	}
+}
+
+func TestParseBetaflightRates(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		want     map[string]RatesConfig
+		throttle *ThrottleConfig
+		wantErr  bool
+	}{
+		{
+			name: "diff with betaflight rates",
+			text: "# diff rateprofile\nrateprofile 0\n\nset rates_type = BETAFLIGHT\nset roll_rc_rate = 120\nset roll_srate = 75\n" +
+				"set pitch_rc_rate = 110\nset yaw_expo = 20\nset thr_mid = 40\nset thr_expo = 30\n",
+			want: map[string]RatesConfig{
+				"roll":  {Model: RatesBetaflight, RcRate: 120, SuperRate: 75},
+				"pitch": {Model: RatesBetaflight, RcRate: 110, SuperRate: 70},
+				"yaw":   {Model: RatesBetaflight, RcRate: 100, Expo: 20, SuperRate: 70},
+			},
+			throttle: &ThrottleConfig{Mid: 40, Expo: 30},
+		},
+		{
+			name: "actual by default",
+			text: "set roll_rc_rate = 20\nset roll_srate = 80\n",
+			want: map[string]RatesConfig{
+				"roll":  {Model: RatesActual, RcRate: 20, SuperRate: 80},
+				"pitch": {Model: RatesActual, RcRate: 7, SuperRate: 67},
+				"yaw":   {Model: RatesActual, RcRate: 7, SuperRate: 67},
+			},
+		},
+		{
+			name: "kiss with throttle mid only",
+			text: "set rates_type = KISS\nset thr_mid = 60\n",
+			want: map[string]RatesConfig{
+				"roll":  {Model: RatesKiss, RcRate: 100, SuperRate: 70},
+				"pitch": {Model: RatesKiss, RcRate: 100, SuperRate: 70},
+				"yaw":   {Model: RatesKiss, RcRate: 100, SuperRate: 70},
+			},
+			throttle: &ThrottleConfig{Mid: 60},
+		},
+		{
+			name: "diff all imports the active rate profile",
+			text: "rateprofile 0\nset rates_type = BETAFLIGHT\nset roll_rc_rate = 120\nset thr_mid = 40\n\n" +
+				"rateprofile 1\nset roll_rc_rate = 20\nset roll_srate = 80\n\n" +
+				"# restore original rateprofile selection\nrateprofile 0\n\nsave\n",
+			want: map[string]RatesConfig{
+				"roll":  {Model: RatesBetaflight, RcRate: 120, SuperRate: 70},
+				"pitch": {Model: RatesBetaflight, RcRate: 100, SuperRate: 70},
+			},
+			throttle: &ThrottleConfig{Mid: 40},
+		},
+		{
+			name: "the last of several rate profiles",
+			text: "rateprofile 0\nset rates_type = BETAFLIGHT\nset roll_rc_rate = 120\nset thr_mid = 40\n" +
+				"rateprofile 1\nset roll_rc_rate = 20\nset roll_srate = 80\n",
+			want: map[string]RatesConfig{
+				"roll":  {Model: RatesActual, RcRate: 20, SuperRate: 80},
+				"pitch": {Model: RatesActual, RcRate: 7, SuperRate: 67},
+			},
+		},
+		{name: "invalid rate profile", text: "rateprofile one\nset roll_rc_rate = 20\n", wantErr: true},
+		{name: "no rates", text: "set motor_pwm_protocol = DSHOT600\nset rates_type = ACTUAL\n", wantErr: true},
+		{name: "unknown model", text: "set rates_type = RACEFLIGHT\nset roll_rc_rate = 50\n", wantErr: true},
+		{name: "out of range", text: "set roll_expo = 150\n", wantErr: true},
+		{name: "throttle out of range", text: "set thr_mid = 120\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseBetaflightRates(tt.text)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error %v, want error %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			for axis, want := range tt.want {
+				if got.Axes[axis] != want {
+					t.Errorf("%s: %+v, want %+v", axis, got.Axes[axis], want)
+				}
+			}
+			if (got.Throttle == nil) != (tt.throttle == nil) || got.Throttle != nil && *got.Throttle != *tt.throttle {
+				t.Errorf("throttle %+v, want %+v", got.Throttle, tt.throttle)
+			}
+		})
+	}
+}
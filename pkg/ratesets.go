@@ -0,0 +1,134 @@
+package helper
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+// RateSetsConfig holds two or three rate sets, e.g. beginner, normal and race, that scale the
+// stick output after the response curves. The flight mode switch picks a set by position, a
+// combo of stick or button conditions cycles through them.
+type RateSetsConfig struct {
+	// Switch selects a set per position: the channel's travel is split evenly among the sets
+	Switch string `json:"switch,omitempty"`
+	// Combo cycles to the next set each time all its conditions become met
+	Combo []GestureCondition `json:"combo,omitempty"`
+	Sets  []RateSet          `json:"sets"`
+}
+
+// RateSet scales roll, pitch and yaw by Rate; Axes overrides the scale per channel and can
+// include the throttle, which is scaled from idle so a lower rate only limits full throttle
+type RateSet struct {
+	Name string             `json:"name"`
+	Rate float64            `json:"rate,omitempty"`
+	Axes map[string]float64 `json:"axes,omitempty"`
+}
+
+// Validate checks the selector and the sets
+func (c *RateSetsConfig) Validate() error {
+	if len(c.Sets) < 2 || len(c.Sets) > 3 {
+		return fmt.Errorf("needs two or three sets, got %d", len(c.Sets))
+	}
+	if c.Switch == "" && len(c.Combo) == 0 {
+		return fmt.Errorf("needs a switch or a combo to select sets")
+	}
+	if c.Switch != "" {
+		if err := validateChannel(c.Switch); err != nil {
+			return fmt.Errorf("switch: %w", err)
+		}
+	}
+	for _, cond := range c.Combo {
+		if err := validateChannel(cond.Channel); err != nil {
+			return fmt.Errorf("combo: %w", err)
+		}
+		if cond.Above == nil && cond.Below == nil {
+			return fmt.Errorf("combo: condition on %s needs above or below", cond.Channel)
+		}
+	}
+	for i, s := range c.Sets {
+		if s.Name == "" {
+			return fmt.Errorf("set %d needs a name", i+1)
+		}
+		if s.Rate < 0 || s.Rate > 1 {
+			return fmt.Errorf("%s: rate %g outside 0 to 1", s.Name, s.Rate)
+		}
+		for name, r := range s.Axes {
+			if err := validateChannel(name); err != nil {
+				return fmt.Errorf("%s: %w", s.Name, err)
+			}
+			if r <= 0 || r > 1 {
+				return fmt.Errorf("%s: rate %g for %s outside 0 to 1", s.Name, r, name)
+			}
+		}
+	}
+	return nil
+}
+
+// rateSelector applies the active rate set and follows the switch and combo
+type rateSelector struct {
+	cfg       RateSetsConfig
+	scales    []map[string]float64
+	active    atomic.Int32
+	switchPos int // last switch position, -1 before the switch was seen
+	comboMet  bool
+}
+
+func newRateSelector(c RateSetsConfig) *rateSelector {
+	r := &rateSelector{cfg: c, switchPos: -1}
+	for _, s := range c.Sets {
+		scales := make(map[string]float64)
+		if s.Rate > 0 {
+			for _, axis := range []string{"roll", "pitch", "yaw"} {
+				scales[DefaultAxisChannels[axis]] = s.Rate
+			}
+		}
+		for name, rate := range s.Axes {
+			scales[name] = rate
+		}
+		r.scales = append(r.scales, scales)
+	}
+	return r
+}
+
+// name returns the active set's name
+func (r *rateSelector) name() string {
+	return r.cfg.Sets[r.active.Load()].Name
+}
+
+// selectSet follows the switch and combo
+func (r *rateSelector) selectSet(channels map[string]int16) {
+	n := len(r.cfg.Sets)
+	if v, ok := channels[r.cfg.Switch]; ok && r.cfg.Switch != "" {
+		// Only a switch change selects a set, so the combo can still cycle
+		if pos := min((int(v)+32768)*n/65536, n-1); pos != r.switchPos {
+			r.switchPos = pos
+			r.active.Store(int32(pos))
+		}
+	}
+	if len(r.cfg.Combo) > 0 {
+		met := true
+		for i := range r.cfg.Combo {
+			met = met && r.cfg.Combo[i].met(channels)
+		}
+		if met && !r.comboMet {
+			r.active.Store((r.active.Load() + 1) % int32(n))
+		}
+		r.comboMet = met
+	}
+}
+
+// scale scales the channels in place by the active set. The throttle is scaled from its low
+// end so the stick still cuts to idle; the other axes are scaled around center.
+func (r *rateSelector) scale(channels map[string]int16) {
+	throttle := DefaultAxisChannels["throttle"]
+	for name, scale := range r.scales[r.active.Load()] {
+		v, ok := channels[name]
+		switch {
+		case !ok:
+		case name == throttle:
+			channels[name] = ClampAxis(-32768 + int32((float64(v)+32768)*scale))
+		default:
+			channels[name] = ClampAxis(int32(float64(v) * scale))
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package helper
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+// Frame reader sizing: the ring holds several maximum sized DUML frames
+const (
+	MaxFrameLength  = 0x3ff
+	minFrameLength  = 13
+	frameRingSize   = 4096
+	frameRingMask   = frameRingSize - 1
+	frameHeaderSize = 4
+)
+
+// FrameReader splits a byte stream into DUML frames. It reads from the transport in bulk
+// into a fixed ring buffer and hands out frames that point into reusable memory, so the
+// steady state read path does not allocate. A returned frame is only valid until the next
+// call to Next or Reset. FrameReader is not safe for concurrent use.
+type FrameReader struct {
+	r       io.Reader
+	ring    [frameRingSize]byte
+	scratch [MaxFrameLength]byte // frames that wrap around the end of the ring are copied here
+	head    uint64               // read position, only ever grows
+	tail    uint64               // write position, only ever grows
+}
+
+// NewFrameReader creates a frame reader on top of the given transport
+func NewFrameReader(r io.Reader) *FrameReader {
+	return &FrameReader{r: r}
+}
+
+// Reset drops all buffered data and switches to a new transport
+func (fr *FrameReader) Reset(r io.Reader) {
+	fr.r = r
+	fr.head, fr.tail = 0, 0
+}
+
+// Transport returns the transport frames are read from
+func (fr *FrameReader) Transport() io.Reader {
+	return fr.r
+}
+
+// Buffered returns the number of bytes read from the transport but not yet returned as frames
+func (fr *FrameReader) Buffered() int {
+	return int(fr.tail - fr.head)
+}
+
+// Next returns the next frame whose start byte, length and header checksum are valid.
+// The CRC16 of the frame is not checked, use ValidatePacket for that.
+// If the transport delivers no data before its read timeout Next returns ErrReadTimeout;
+// already buffered bytes are kept, so calling Next again resumes the partial frame.
+func (fr *FrameReader) Next() ([]byte, error) {
+	for {
+		// Find the start byte
+		for fr.head < fr.tail && fr.ring[fr.head&frameRingMask] != 0x55 {
+			fr.head++
+		}
+		if fr.Buffered() < frameHeaderSize {
+			if err := fr.fill(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		// Check the header before trusting its length
+		var header [frameHeaderSize]byte
+		fr.peek(header[:])
+		length := int(binary.LittleEndian.Uint16(header[1:3]) & 0x03FF)
+		if length < minFrameLength || CalcPkt55HdrChecksum(0x77, header[:], 3) != header[3] {
+			fr.head++
+			continue
+		}
+
+		for fr.Buffered() < length {
+			if err := fr.fill(); err != nil {
+				return nil, err
+			}
+		}
+
+		start := int(fr.head & frameRingMask)
+		fr.head += uint64(length)
+		if start+length <= frameRingSize {
+			return fr.ring[start : start+length], nil
+		}
+		n := copy(fr.scratch[:], fr.ring[start:])
+		copy(fr.scratch[n:length], fr.ring[:length-n])
+		return fr.scratch[:length], nil
+	}
+}
+
+// peek copies the oldest buffered bytes into p without consuming them
+func (fr *FrameReader) peek(p []byte) {
+	start := int(fr.head & frameRingMask)
+	n := copy(p, fr.ring[start:])
+	copy(p[n:], fr.ring[:])
+}
+
+// fill performs one bulk read from the transport into the free space of the ring
+func (fr *FrameReader) fill() error {
+	if fr.Buffered() == frameRingSize {
+		// A full ring without a frame means garbage, start over
+		fr.head = fr.tail
+	}
+
+	start := int(fr.tail & frameRingMask)
+	end := frameRingSize
+	if wrapped := int(fr.head & frameRingMask); fr.head != fr.tail && wrapped > start {
+		end = wrapped
+	}
+
+	n, err := fr.r.Read(fr.ring[start:end])
+	fr.tail += uint64(n)
+	if n > 0 {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return ErrReadTimeout
+}
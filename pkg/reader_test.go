@@ -0,0 +1,80 @@
+package helper
+
+import (
+	"testing"
+)
+
+// loopTransport replays a byte stream forever, delivering at most chunk bytes per read
+// like a serial port that returns whatever arrived since the last read
+type loopTransport struct {
+	data  []byte
+	pos   int
+	chunk int
+}
+
+func (t *loopTransport) Read(p []byte) (int, error) {
+	n := min(len(p), t.chunk, len(t.data)-t.pos)
+	copy(p, t.data[t.pos:t.pos+n])
+	t.pos = (t.pos + n) % len(t.data)
+	return n, nil
+}
+
+// channelReplyStream builds a stream of 38-byte channel replies as sent by the RC
+func channelReplyStream(b *testing.B, frames int) []byte {
+	var stream []byte
+	for i := range frames {
+		packet, err := BuildDUML(uint16(i), 0x06, 0x0a, 0x40, 0x06, 0x01, make([]byte, 25))
+		if err != nil {
+			b.Fatal(err)
+		}
+		stream = append(stream, packet...)
+	}
+	return stream
+}
+
+func benchmarkFrameReader(b *testing.B, chunk int) {
+	// 37 frames make the stream length coprime with the ring size, so frames also wrap around
+	transport := &loopTransport{data: channelReplyStream(b, 37), chunk: chunk}
+	reader := NewFrameReader(transport)
+
+	b.ReportAllocs()
+	b.SetBytes(38)
+	b.ResetTimer()
+	for range b.N {
+		frame, err := reader.Next()
+		if err != nil {
+			b.Fatal(err)
+		}
+		if err := ValidatePacket(frame); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkFrameReaderSingleFrameReads(b *testing.B) { benchmarkFrameReader(b, 38) }
+func BenchmarkFrameReaderBulkReads(b *testing.B)        { benchmarkFrameReader(b, 4096) }
+func BenchmarkFrameReaderSplitReads(b *testing.B)       { benchmarkFrameReader(b, 7) }
+
+// BenchmarkReadPacketHeader measures the byte-by-byte read path for comparison
+func BenchmarkReadPacketHeader(b *testing.B) {
+	transport := &loopTransport{data: channelReplyStream(b, 37), chunk: 38}
+	buffer := make([]byte, 1024)
+
+	b.ReportAllocs()
+	b.SetBytes(38)
+	b.ResetTimer()
+	for range b.N {
+		packet, length, err := ReadPacketHeader(transport, buffer, 0)
+		if err != nil {
+			b.Fatal(err)
+		}
+		n, err := ReadBytes(transport, buffer, int(length)-4, 0)
+		if err != nil {
+			b.Fatal(err)
+		}
+		packet = append(packet, buffer[:n]...)
+		if err := ValidatePacket(packet); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package helper
+
+import (
+	"fmt"
+	"slices"
+)
+
+// ShiftConfig is a second set of button rules used while a modifier input is held. Routes to
+// sticks and triggers stay active, only the button mappings are swapped, so a few RC controls
+// can reach every gamepad button.
+type ShiftConfig struct {
+	// Channel and Enter define the modifier: held while the channel is past Enter, released
+	// again once it is back inside half of Enter
+	Channel string       `json:"channel"`
+	Enter   int16        `json:"enter"`
+	Buttons []ButtonRule `json:"buttons,omitempty"`
+	Dpad    *DpadConfig  `json:"dpad,omitempty"`
+}
+
+// Validate checks the modifier and the shifted button rules
+func (s *ShiftConfig) Validate() error {
+	if err := validateChannel(s.Channel); err != nil {
+		return err
+	}
+	if s.Enter == 0 {
+		return fmt.Errorf("enter threshold must not be 0")
+	}
+	for i, b := range s.Buttons {
+		if err := b.Validate(); err != nil {
+			return fmt.Errorf("button rule %d: %w", i+1, err)
+		}
+	}
+	if s.Dpad != nil {
+		if err := s.Dpad.Validate(); err != nil {
+			return fmt.Errorf("dpad: %w", err)
+		}
+	}
+	return nil
+}
+
+// rules returns the shifted button rules including the D-pad's
+func (s *ShiftConfig) rules() []ButtonRule {
+	rules := s.Buttons
+	if s.Dpad != nil {
+		rules = append(slices.Clip(rules), s.Dpad.Rules()...)
+	}
+	return rules
+}
+
+// shiftLayer is a compiled ShiftConfig with the modifier's state
+type shiftLayer struct {
+	channel string
+	enter   int16
+	rules   []*buttonRule
+	held    bool
+}
+
+// update feeds the modifier its channel value and reports whether it changed
+func (s *shiftLayer) update(v int16) bool {
+	was := s.held
+	if s.enter > 0 {
+		s.held = v >= s.enter || s.held && v > s.enter/2
+	} else {
+		s.held = v <= s.enter || s.held && v < s.enter/2
+	}
+	return s.held != was
+}
@@ -0,0 +1,73 @@
+package helper
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Slew limit directions
+const (
+	SlewBoth = "both" // limit changes in both directions
+	SlewUp   = "up"   // limit only rising values
+	SlewDown = "down" // limit only falling values, e.g. a throttle cut
+)
+
+// SlewLimit caps how fast a channel may change, to soften stick slams
+type SlewLimit struct {
+	// UnitsPerSecond is the largest change per second; the full stick travel is 65535 units
+	UnitsPerSecond float64 `json:"units_per_s"`
+	// Direction is both (default), up or down
+	Direction string `json:"direction,omitempty"`
+}
+
+// Validate checks the rate and direction
+func (l *SlewLimit) Validate() error {
+	if l.UnitsPerSecond <= 0 {
+		return fmt.Errorf("units_per_s must be positive")
+	}
+	switch strings.ToLower(l.Direction) {
+	case "", SlewBoth, SlewUp, SlewDown:
+	default:
+		return fmt.Errorf("unknown direction %q (want both, up or down)", l.Direction)
+	}
+	return nil
+}
+
+// slewLimiter is a compiled SlewLimit with the last value it output
+type slewLimiter struct {
+	rate     float64
+	up, down bool // which changes are limited
+	last     float64
+	at       time.Time
+}
+
+func newSlewLimiter(l SlewLimit) *slewLimiter {
+	dir := strings.ToLower(l.Direction)
+	return &slewLimiter{
+		rate: l.UnitsPerSecond,
+		up:   dir != SlewDown,
+		down: dir != SlewUp,
+	}
+}
+
+// limit returns the value moved from the last output towards v by at most the allowed change
+func (s *slewLimiter) limit(now time.Time, v int16) int16 {
+	if s.at.IsZero() {
+		s.last, s.at = float64(v), now
+		return v
+	}
+	step := s.rate * max(now.Sub(s.at).Seconds(), 0)
+	s.at = now
+
+	target := float64(v)
+	switch {
+	case target > s.last && s.up:
+		s.last = min(target, s.last+step)
+	case target < s.last && s.down:
+		s.last = max(target, s.last-step)
+	default:
+		s.last = target
+	}
+	return ClampAxis(int32(s.last))
+}
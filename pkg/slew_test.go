@@ -0,0 +1,74 @@
+package helper
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+// slewOutputs feeds a limiter one value per 10 ms frame and returns its outputs
+func slewOutputs(l SlewLimit, values ...int16) []int16 {
+	s := newSlewLimiter(l)
+	start := time.Unix(1000, 0)
+	out := make([]int16, len(values))
+	for i, v := range values {
+		out[i] = s.limit(start.Add(time.Duration(i)*10*time.Millisecond), v)
+	}
+	return out
+}
+
+func TestSlewLimiter(t *testing.T) {
+	// 100000 units/s allow 1000 units per 10 ms frame
+	tests := []struct {
+		name   string
+		limit  SlewLimit
+		values []int16
+		want   []int16
+	}{
+		{"first value passes", SlewLimit{UnitsPerSecond: 100000}, []int16{30000}, []int16{30000}},
+		{"both directions", SlewLimit{UnitsPerSecond: 100000},
+			[]int16{0, 2500, 2500, 2500, -1000, -1000},
+			[]int16{0, 1000, 2000, 2500, 1500, 500}},
+		{"small changes pass", SlewLimit{UnitsPerSecond: 100000}, []int16{0, 800, 200}, []int16{0, 800, 200}},
+		{"up only", SlewLimit{UnitsPerSecond: 100000, Direction: "up"},
+			[]int16{-32768, 32767, 32767, -32768},
+			[]int16{-32768, -31768, -30768, -32768}},
+		{"down only cuts slowly", SlewLimit{UnitsPerSecond: 100000, Direction: "Down"},
+			[]int16{32767, -32768, -32768, 32767},
+			[]int16{32767, 31767, 30767, 32767}},
+		{"full travel stays in range", SlewLimit{UnitsPerSecond: 1e7},
+			[]int16{-32768, 32767, -32768},
+			[]int16{-32768, 32767, -32768}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := slewOutputs(tt.limit, tt.values...); !slices.Equal(got, tt.want) {
+				t.Errorf("outputs %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSlewLimiterUsesElapsedTime(t *testing.T) {
+	s := newSlewLimiter(SlewLimit{UnitsPerSecond: 100000})
+	start := time.Unix(1000, 0)
+	s.limit(start, 0)
+	if got := s.limit(start.Add(25*time.Millisecond), 10000); got != 2500 {
+		t.Errorf("after 25 ms: %d, want 2500", got)
+	}
+	// A clock step backwards allows no change rather than a negative one
+	if got := s.limit(start, 10000); got != 2500 {
+		t.Errorf("after going back in time: %d, want 2500", got)
+	}
+}
+
+func TestSlewLimitValidate(t *testing.T) {
+	for _, l := range []SlewLimit{{UnitsPerSecond: 0}, {UnitsPerSecond: -5}, {UnitsPerSecond: 100, Direction: "sideways"}} {
+		if err := l.Validate(); err == nil {
+			t.Errorf("%+v accepted", l)
+		}
+	}
+	if l := (SlewLimit{UnitsPerSecond: 100, Direction: "DOWN"}); l.Validate() != nil {
+		t.Errorf("%+v rejected", l)
+	}
+}
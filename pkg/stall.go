@@ -0,0 +1,66 @@
+package helper
+
+import (
+	"sync"
+	"time"
+)
+
+// StallDetector notices when the RC stops replying to channel requests.
+// It is safe for concurrent use.
+type StallDetector struct {
+	mu          sync.Mutex
+	timeout     time.Duration
+	lastReply   time.Time
+	lastAttempt time.Time
+	stalled     bool
+}
+
+// NewStallDetector creates a detector that reports a stall after timeout without replies
+func NewStallDetector(timeout time.Duration) *StallDetector {
+	return &StallDetector{timeout: timeout, lastReply: time.Now()}
+}
+
+// Timeout returns the silence after which the RC counts as stalled
+func (d *StallDetector) Timeout() time.Duration {
+	return d.timeout
+}
+
+// Reply registers a reply from the RC. If the RC was stalled it returns how long it was silent.
+func (d *StallDetector) Reply(at time.Time) (resumedAfter time.Duration) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.stalled {
+		resumedAfter = at.Sub(d.lastReply)
+		d.stalled = false
+	}
+	d.lastReply = at
+	return resumedAfter
+}
+
+// Check reports how long the RC has been silent and whether it is stalled.
+// retry is true when a stall begins and then once per timeout while it lasts,
+// telling the caller to attempt a recovery.
+func (d *StallDetector) Check(now time.Time) (silence time.Duration, stalled, retry bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	silence = now.Sub(d.lastReply)
+	if silence < d.timeout {
+		return silence, false, false
+	}
+
+	if !d.stalled || now.Sub(d.lastAttempt) >= d.timeout {
+		retry = true
+		d.lastAttempt = now
+	}
+	d.stalled = true
+	return silence, true, retry
+}
+
+// Stalled reports whether the RC is currently considered stalled
+func (d *StallDetector) Stalled() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.stalled
+}
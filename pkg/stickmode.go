@@ -0,0 +1,96 @@
+package helper
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// StickMode is a transmitter stick layout, numbered like the usual Mode 1 to 4 conventions.
+// Profiles, curves and imported rates are written for Mode 2; the other modes are remapped
+// onto it before shaping so every profile works with every layout.
+type StickMode int
+
+const (
+	StickMode1 StickMode = iota + 1 // left stick pitch and yaw, right stick throttle and roll
+	StickMode2                      // left stick throttle and yaw, right stick pitch and roll
+	StickMode3                      // left stick pitch and roll, right stick throttle and yaw
+	StickMode4                      // left stick throttle and roll, right stick pitch and yaw
+)
+
+// stickChannels lists the physical stick channels in the order of the flight axes in stickLayouts
+var stickChannels = [4]string{"left_vertical", "left_horizontal", "right_vertical", "right_horizontal"}
+
+// stickLayouts gives the flight axis on each physical stick channel, indexed like stickChannels
+var stickLayouts = map[StickMode][4]string{
+	StickMode1: {"pitch", "yaw", "throttle", "roll"},
+	StickMode2: {"throttle", "yaw", "pitch", "roll"},
+	StickMode3: {"pitch", "roll", "throttle", "yaw"},
+	StickMode4: {"throttle", "roll", "pitch", "yaw"},
+}
+
+func (m StickMode) String() string {
+	return fmt.Sprintf("Mode %d", int(m))
+}
+
+// ParseStickMode parses a mode number such as "2" or "mode2"
+func ParseStickMode(name string) (StickMode, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	n, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(name, "mode")))
+	if err != nil || n < 1 || n > 4 {
+		return 0, fmt.Errorf("unknown stick mode %q (want 1 to 4)", name)
+	}
+	return StickMode(n), nil
+}
+
+// AxisChannels returns the physical channel carrying each flight axis (roll, pitch, yaw, throttle)
+func (m StickMode) AxisChannels() map[string]string {
+	layout, ok := stickLayouts[m]
+	if !ok {
+		layout = stickLayouts[StickMode2]
+	}
+	channels := make(map[string]string, len(layout))
+	for i, axis := range layout {
+		channels[axis] = stickChannels[i]
+	}
+	return channels
+}
+
+// Remap moves the stick channel values in place so each flight axis ends up on the channel
+// it uses in Mode 2. Mode 2 leaves the values untouched.
+func (m StickMode) Remap(channels map[string]int16) {
+	layout, ok := stickLayouts[m]
+	if !ok || m == StickMode2 {
+		return
+	}
+	var values [4]int16
+	var present [4]bool
+	for i, name := range stickChannels {
+		values[i], present[i] = channels[name]
+	}
+	target := stickLayouts[StickMode2]
+	for i, axis := range layout {
+		if !present[i] {
+			continue
+		}
+		for j, a := range target {
+			if a == axis {
+				channels[stickChannels[j]] = values[i]
+			}
+		}
+	}
+}
+
+// ProfileChannel returns the channel a physical channel's value lands on after Remap
+func (m StickMode) ProfileChannel(name string) string {
+	layout, ok := stickLayouts[m]
+	if !ok || m == StickMode2 {
+		return name
+	}
+	i := slices.Index(stickChannels[:], name)
+	if i < 0 {
+		return name
+	}
+	return StickMode2.AxisChannels()[layout[i]]
+}
@@ -0,0 +1,169 @@
+package helper
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Trim defaults and limits
+const (
+	MaxTrim          = 8192 // a quarter of the stick's half travel
+	defaultTrimStep  = 64
+	defaultTrimEnter = 16384
+)
+
+// TrimControl adjusts the trim of one axis live. Deflecting Channel past Enter steps the trim
+// up, past -Enter down, and holding it repeats the step. With a Modifier the control only
+// trims while the modifier condition is met, so e.g. the camera dial can trim while a button
+// is held and drive buttons otherwise.
+type TrimControl struct {
+	// Axis is the stick channel being trimmed
+	Axis    string `json:"axis"`
+	Channel string `json:"channel"`
+	// Enter is the deflection that steps the trim (default 16384)
+	Enter int16 `json:"enter,omitempty"`
+	// Step is the trim change per step (default 64)
+	Step     int16             `json:"step,omitempty"`
+	Modifier *GestureCondition `json:"modifier,omitempty"`
+}
+
+// Validate checks the control's channels and settings
+func (c *TrimControl) Validate() error {
+	if err := validateChannel(c.Axis); err != nil {
+		return fmt.Errorf("axis: %w", err)
+	}
+	if err := validateChannel(c.Channel); err != nil {
+		return err
+	}
+	if c.Enter < 0 || c.Step < 0 || c.Step > MaxTrim {
+		return fmt.Errorf("enter and step must be positive and step at most %d", MaxTrim)
+	}
+	if m := c.Modifier; m != nil {
+		if err := validateChannel(m.Channel); err != nil {
+			return fmt.Errorf("modifier: %w", err)
+		}
+		if m.Above == nil && m.Below == nil {
+			return fmt.Errorf("modifier needs above or below")
+		}
+	}
+	return nil
+}
+
+// validateTrims checks that trims name known channels and stay within MaxTrim
+func validateTrims(trims map[string]int16) error {
+	for name, v := range trims {
+		if err := validateChannel(name); err != nil {
+			return err
+		}
+		if v > MaxTrim || v < -MaxTrim {
+			return fmt.Errorf("%s: trim %d exceeds ±%d", name, v, MaxTrim)
+		}
+	}
+	return nil
+}
+
+// TrimPercent expresses a trim as a percentage of the stick's half travel
+func TrimPercent(v int16) float64 {
+	return float64(v) * 100 / 32767
+}
+
+// trimControl is a compiled TrimControl with its runtime state
+type trimControl struct {
+	cfg        TrimControl
+	dir        int // -1, 0 or 1 while the channel is deflected
+	nextRepeat time.Time
+}
+
+// Trimmer offsets stick channels by their trims and adjusts the trims with trim controls
+type Trimmer struct {
+	mutex    sync.Mutex // guards trims
+	trims    map[string]int16
+	controls []*trimControl
+}
+
+// NewTrimmer starts from a profile's saved trims
+func NewTrimmer(trims map[string]int16, controls []TrimControl) *Trimmer {
+	t := &Trimmer{trims: make(map[string]int16, len(trims))}
+	for name, v := range trims {
+		t.trims[name] = v
+	}
+	for _, c := range controls {
+		if c.Enter == 0 {
+			c.Enter = defaultTrimEnter
+		}
+		if c.Step == 0 {
+			c.Step = defaultTrimStep
+		}
+		t.controls = append(t.controls, &trimControl{cfg: c})
+	}
+	return t
+}
+
+// Trims returns a copy of the current trims
+func (t *Trimmer) Trims() map[string]int16 {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	trims := make(map[string]int16, len(t.trims))
+	for name, v := range t.trims {
+		if v != 0 {
+			trims[name] = v
+		}
+	}
+	return trims
+}
+
+// Apply runs the trim controls on the untrimmed channel values, then offsets the channels by
+// their trims in place. It returns the axes whose trim changed.
+func (t *Trimmer) Apply(now time.Time, channels map[string]int16) []string {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	var changed []string
+	for _, c := range t.controls {
+		if step := c.update(now, channels); step != 0 {
+			old := t.trims[c.cfg.Axis]
+			t.trims[c.cfg.Axis] = int16(min(max(int32(old)+int32(step), -MaxTrim), MaxTrim))
+			if t.trims[c.cfg.Axis] != old {
+				changed = append(changed, c.cfg.Axis)
+			}
+		}
+	}
+	for name, v := range t.trims {
+		if cv, ok := channels[name]; ok && v != 0 {
+			channels[name] = ClampAxis(int32(cv) + int32(v))
+		}
+	}
+	return changed
+}
+
+// update returns the trim step the control makes at time now, if any
+func (c *trimControl) update(now time.Time, channels map[string]int16) int16 {
+	if c.cfg.Modifier != nil && !c.cfg.Modifier.met(channels) {
+		c.dir = 0
+		return 0
+	}
+
+	v := channels[c.cfg.Channel]
+	dir := 0
+	switch {
+	case v >= c.cfg.Enter || c.dir > 0 && v > c.cfg.Enter/2:
+		dir = 1
+	case v <= -c.cfg.Enter || c.dir < 0 && v < -c.cfg.Enter/2:
+		dir = -1
+	}
+
+	switch {
+	case dir == 0:
+		c.dir = 0
+		return 0
+	case dir != c.dir:
+		c.dir = dir
+		c.nextRepeat = now.Add(defaultRepeatDelay)
+	case !now.Before(c.nextRepeat):
+		c.nextRepeat = now.Add(defaultRepeatInterval)
+	default:
+		return 0
+	}
+	return int16(dir) * c.cfg.Step
+}
@@ -0,0 +1,119 @@
+package helper
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// UpsampleMode selects how stick values are estimated between RC frames
+type UpsampleMode int
+
+const (
+	UpsampleInterpolate UpsampleMode = iota // glide from the previous to the latest frame, one frame behind
+	UpsampleExtrapolate                     // continue from the latest frame along the stick's velocity
+)
+
+var upsampleModeNames = map[UpsampleMode]string{
+	UpsampleInterpolate: "interpolate",
+	UpsampleExtrapolate: "extrapolate",
+}
+
+func (m UpsampleMode) String() string {
+	if name, ok := upsampleModeNames[m]; ok {
+		return name
+	}
+	return fmt.Sprintf("UpsampleMode(%d)", int(m))
+}
+
+// ParseUpsampleMode parses "interpolate" or "extrapolate"
+func ParseUpsampleMode(name string) (UpsampleMode, error) {
+	for mode, n := range upsampleModeNames {
+		if strings.EqualFold(name, n) {
+			return mode, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown output mode %q (want interpolate or extrapolate)", name)
+}
+
+// maxExtrapolation caps how far ahead of the latest frame values are extrapolated
+const maxExtrapolation = 20 * time.Millisecond
+
+// upsampleTrack holds the last three frames of one channel, newest first
+type upsampleTrack struct {
+	t [3]time.Time
+	v [3]float64
+	n int
+}
+
+func (tr *upsampleTrack) push(t time.Time, v int16) {
+	if tr.n > 0 && t.Sub(tr.t[0]) > filterMaxSampleGap {
+		// Too old to estimate a movement from
+		tr.n = 0
+	}
+	copy(tr.t[1:], tr.t[:2])
+	copy(tr.v[1:], tr.v[:2])
+	tr.t[0], tr.v[0] = t, float64(v)
+	tr.n = min(tr.n+1, 3)
+}
+
+// at estimates the channel value at time now
+func (tr *upsampleTrack) at(mode UpsampleMode, now time.Time) float64 {
+	if tr.n < 2 {
+		return tr.v[0]
+	}
+	interval := tr.t[0].Sub(tr.t[1])
+	if interval <= 0 {
+		return tr.v[0]
+	}
+	since := now.Sub(tr.t[0])
+	step := tr.v[0] - tr.v[1]
+
+	if mode == UpsampleInterpolate {
+		// Replay the last frame interval, ending on the latest value
+		f := min(max(since.Seconds()/interval.Seconds(), 0), 1)
+		return tr.v[1] + step*f
+	}
+
+	// A reversal is not extrapolated, so the output never overshoots a turning point
+	if tr.n == 3 && step*(tr.v[1]-tr.v[2]) < 0 {
+		return tr.v[0]
+	}
+	since = min(max(since, 0), interval, maxExtrapolation)
+	return tr.v[0] + step*since.Seconds()/interval.Seconds()
+}
+
+// Upsampler estimates stick channel values between RC frames so the gamepad can be updated
+// faster than the RC reports. Buttons and switches are passed through unchanged. It is not
+// safe for concurrent use.
+type Upsampler struct {
+	mode   UpsampleMode
+	tracks map[string]*upsampleTrack
+}
+
+// NewUpsampler creates an upsampler for the stick and dial channels
+func NewUpsampler(mode UpsampleMode) *Upsampler {
+	u := &Upsampler{mode: mode, tracks: make(map[string]*upsampleTrack, len(KnownChannels))}
+	for _, name := range KnownChannels {
+		u.tracks[name] = &upsampleTrack{}
+	}
+	return u
+}
+
+// Push adds the channel values of an RC frame completed at time t
+func (u *Upsampler) Push(t time.Time, channels map[string]int16) {
+	for name, tr := range u.tracks {
+		if v, ok := channels[name]; ok {
+			tr.push(t, v)
+		}
+	}
+}
+
+// Sample replaces the stick channel values in place with their estimates at time now
+func (u *Upsampler) Sample(now time.Time, channels map[string]int16) {
+	for name, tr := range u.tracks {
+		if _, ok := channels[name]; ok && tr.n > 0 {
+			channels[name] = ClampAxis(int32(tr.at(u.mode, now)))
+		}
+	}
+}
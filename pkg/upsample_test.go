@@ -0,0 +1,64 @@
+package helper
+
+import (
+	"testing"
+	"time"
+)
+
+// newTrack pushes the values as frames spaced interval apart, returning the track and the time
+// of the latest frame
+func newTrack(interval time.Duration, values ...int16) (*upsampleTrack, time.Time) {
+	tr := &upsampleTrack{}
+	t := time.Unix(1000, 0)
+	for i, v := range values {
+		if i > 0 {
+			t = t.Add(interval)
+		}
+		tr.push(t, v)
+	}
+	return tr, t
+}
+
+func TestUpsampleTrackAt(t *testing.T) {
+	ms := time.Millisecond
+	tests := []struct {
+		name     string
+		mode     UpsampleMode
+		interval time.Duration
+		values   []int16
+		since    time.Duration
+		want     float64
+	}{
+		{"interpolate halfway", UpsampleInterpolate, 10 * ms, []int16{0, 1000, 2000}, 5 * ms, 1500},
+		{"interpolate at the frame", UpsampleInterpolate, 10 * ms, []int16{0, 1000, 2000}, 0, 1000},
+		{"interpolate ends on the latest", UpsampleInterpolate, 10 * ms, []int16{0, 1000, 2000}, 30 * ms, 2000},
+		{"extrapolate halfway", UpsampleExtrapolate, 10 * ms, []int16{0, 1000, 2000}, 5 * ms, 2500},
+		{"extrapolate capped at one interval", UpsampleExtrapolate, 10 * ms, []int16{0, 1000, 2000}, 50 * ms, 3000},
+		{"extrapolate capped at 20 ms", UpsampleExtrapolate, 40 * ms, []int16{0, 1000, 2000}, 30 * ms, 2500},
+		{"extrapolate holds a reversal", UpsampleExtrapolate, 10 * ms, []int16{0, 1000, 500}, 5 * ms, 500},
+		{"extrapolate with two frames", UpsampleExtrapolate, 10 * ms, []int16{1000, 500}, 5 * ms, 250},
+		{"single frame", UpsampleExtrapolate, 10 * ms, []int16{700}, 5 * ms, 700},
+		{"gap restarts the track", UpsampleExtrapolate, 300 * ms, []int16{0, 1000}, 5 * ms, 1000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr, last := newTrack(tt.interval, tt.values...)
+			if got := tr.at(tt.mode, last.Add(tt.since)); got != tt.want {
+				t.Errorf("at +%v = %g, want %g", tt.since, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpsamplerPassesOtherChannels(t *testing.T) {
+	u := NewUpsampler(UpsampleExtrapolate)
+	start := time.Unix(1000, 0)
+	u.Push(start, map[string]int16{"right_horizontal": 0, "extra": 5})
+	u.Push(start.Add(10*time.Millisecond), map[string]int16{"right_horizontal": 1000, "extra": 9})
+
+	channels := map[string]int16{"right_horizontal": 1000, "extra": 9}
+	u.Sample(start.Add(15*time.Millisecond), channels)
+	if channels["right_horizontal"] != 1500 || channels["extra"] != 9 {
+		t.Errorf("sampled %v, want right_horizontal 1500 and extra unchanged", channels)
+	}
+}
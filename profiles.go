@@ -0,0 +1,284 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	helper "github.com/CB2Moon/DJI_RC_Nx_Translator/pkg"
+	"github.com/CB2Moon/vgamepad-go/pkg/vgamepad"
+)
+
+// defaultProfileName is the profile built into the executable
+const defaultProfileName = "default"
+
+var (
+	profiles     []*helper.Profile
+	activeMapper *helper.Mapper
+	profileMutex sync.Mutex // guards profiles and activeMapper
+)
+
+// loadProfiles loads the built-in default profile and all profiles in the profiles directory.
+// A profile on disk replaces a built-in one of the same name.
+func loadProfiles(dir string) error {
+	data, err := embeddedAssets.ReadFile("assets/profiles/default.json")
+	if err != nil {
+		return err
+	}
+	builtIn, err := helper.ParseProfile(data)
+	if err != nil {
+		return fmt.Errorf("built-in profile: %w", err)
+	}
+
+	loaded := []*helper.Profile{builtIn}
+	fromDisk, errs := helper.LoadProfiles(dir)
+	for _, err := range errs {
+		uiLogger("Skipping profile %v", err)
+	}
+	for _, p := range fromDisk {
+		replaced := false
+		for i, existing := range loaded {
+			if existing.Name == p.Name {
+				loaded[i] = p
+				replaced = true
+			}
+		}
+		if !replaced {
+			loaded = append(loaded, p)
+		}
+	}
+
+	profileMutex.Lock()
+	profiles = loaded
+	profileMutex.Unlock()
+	return nil
+}
+
+// profileNames returns the names of all loaded profiles
+func profileNames() []string {
+	profileMutex.Lock()
+	defer profileMutex.Unlock()
+
+	names := make([]string, len(profiles))
+	for i, p := range profiles {
+		names[i] = p.Name
+	}
+	return names
+}
+
+// selectProfile activates a loaded profile by name, or loads one from a file path
+func selectProfile(nameOrPath string) error {
+	var selected *helper.Profile
+
+	profileMutex.Lock()
+	for _, p := range profiles {
+		if p.Name == nameOrPath {
+			selected = p
+		}
+	}
+	profileMutex.Unlock()
+
+	if selected == nil {
+		if _, err := os.Stat(nameOrPath); err != nil {
+			return fmt.Errorf("profile %q not found", nameOrPath)
+		}
+		p, err := helper.LoadProfile(nameOrPath)
+		if err != nil {
+			return err
+		}
+		selected = p
+
+		profileMutex.Lock()
+		profiles = append(profiles, p)
+		profileMutex.Unlock()
+	}
+
+	mapper, err := helper.NewMapper(selected)
+	if err != nil {
+		return fmt.Errorf("profile %q: %w", selected.Name, err)
+	}
+
+	profileMutex.Lock()
+	activeMapper = mapper
+	profileMutex.Unlock()
+
+	uiLogger("Using profile %q", selected.Name)
+	logProfileTrims(selected)
+	logFilterDelays(selected, "")
+	return nil
+}
+
+// currentMapper returns the mapper of the active profile
+func currentMapper() *helper.Mapper {
+	profileMutex.Lock()
+	defer profileMutex.Unlock()
+	return activeMapper
+}
+
+// exportDefaultProfile writes the built-in profile into the profiles directory as a starting
+// point for custom profiles, unless the directory already exists
+func exportDefaultProfile(dir string) {
+	if _, err := os.Stat(dir); err == nil {
+		return
+	}
+	data, err := embeddedAssets.ReadFile("assets/profiles/default.json")
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		uiLogger("Could not create profiles directory: %v", err)
+		return
+	}
+	if err := os.WriteFile(filepath.Join(dir, "default.json"), data, 0644); err != nil {
+		uiLogger("Could not export default profile: %v", err)
+	}
+}
+
+// safeFileName turns a profile name into a file name stem: anything but ASCII letters, digits,
+// underscores and hyphens becomes an underscore, so names cannot leave the directory
+func safeFileName(name string) string {
+	safe := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '-':
+			return r
+		}
+		return '_'
+	}, name)
+	if safe == "" {
+		return "unnamed"
+	}
+	return safe
+}
+
+// profileSavePath returns the file a changed profile is saved to: the one it was loaded from,
+// or for a built-in profile a new file in the profiles directory
+func profileSavePath(p *helper.Profile) string {
+	if p.Path != "" {
+		return p.Path
+	}
+	return filepath.Join(*profilesDir, safeFileName(p.Name)+".json")
+}
+
+// exportCurves logs the response curves of the active profile and writes them to a CSV file
+func exportCurves() {
+	mapper := currentMapper()
+	curves := mapper.Curves()
+	if len(curves) == 0 {
+		uiLogger("Profile %q has no response curves, all channels are linear", mapper.Profile().Name)
+		return
+	}
+
+	for _, name := range helper.KnownChannels {
+		if c, ok := curves[name]; ok {
+			uiLogger("Curve %s: %s", name, helper.DescribeCurve(c))
+		}
+	}
+
+	path := fmt.Sprintf("curves_%s.csv", safeFileName(mapper.Profile().Name))
+	f, err := os.Create(path)
+	if err != nil {
+		uiLogger("Error exporting curves: %v", err)
+		return
+	}
+	defer f.Close()
+	if err := helper.WriteCurvesCSV(f, curves, 200); err != nil {
+		uiLogger("Error exporting curves: %v", err)
+		return
+	}
+	uiLogger("Response curves exported to %s", path)
+}
+
+// importRates applies Betaflight CLI rate settings to the active profile's stick curves and
+// saves the profile back to its file so the rates survive a restart
+func importRates(text string) error {
+	rates, err := helper.ParseBetaflightRates(text)
+	if err != nil {
+		return err
+	}
+	for axis, cfg := range rates.Axes {
+		cfg.MaxRate = *ratesMaxRate
+		rates.Axes[axis] = cfg
+	}
+
+	active := currentMapper()
+	current := active.Profile()
+	updated := *current
+	updated.Trims = active.Trims()
+	updated.Curves = make(map[string]helper.CurveConfig, len(current.Curves))
+	for name, c := range current.Curves {
+		updated.Curves[name] = c
+	}
+	rates.ApplyTo(&updated, helper.DefaultAxisChannels)
+
+	mapper, err := helper.NewMapper(&updated)
+	if err != nil {
+		return err
+	}
+	path := profileSavePath(current)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	if err := updated.Save(path); err != nil {
+		return err
+	}
+	updated.Path = path
+
+	profileMutex.Lock()
+	for i, p := range profiles {
+		// By file, since saving trims may have replaced the active profile in the list
+		if profileSavePath(p) == path {
+			profiles[i] = &updated
+			break
+		}
+	}
+	activeMapper = mapper
+	profileMutex.Unlock()
+
+	for _, axis := range []string{"roll", "pitch", "yaw", "throttle"} {
+		channel := helper.DefaultAxisChannels[axis]
+		if c, ok := mapper.Curves()[channel]; ok {
+			uiLogger("Rates %s (%s): %s", axis, channel, helper.DescribeCurve(c))
+		}
+	}
+	uiLogger("Imported rates into profile %q, saved to %s", updated.Name, path)
+	return nil
+}
+
+// modeName names the flight or menu mapping
+func modeName(menu bool) string {
+	if menu {
+		return "menu mode"
+	}
+	return "flight mode"
+}
+
+// runningStatus is the status label text while translating, naming the active mapping of
+// profiles that have a menu mapping and the active rate set of profiles that have rate sets
+func runningStatus() string {
+	mapper := currentMapper()
+	status := "Running"
+	if mapper.HasMenu() {
+		status += " - " + modeName(mapper.MenuMode())
+	}
+	if set := mapper.RateSet(); set != "" {
+		status += " - " + set + " rates"
+	}
+	return status
+}
+
+// applyGamepadState writes a complete report into the virtual gamepad
+func applyGamepadState(gp *vgamepad.VX360Gamepad, state helper.GamepadState) {
+	gp.LeftJoystick(state.Axes[helper.AxisLeftX], state.Axes[helper.AxisLeftY])
+	gp.RightJoystick(state.Axes[helper.AxisRightX], state.Axes[helper.AxisRightY])
+	gp.LeftTrigger(state.Triggers[helper.TriggerLeft])
+	gp.RightTrigger(state.Triggers[helper.TriggerRight])
+	for _, b := range helper.AllButtons {
+		if state.Pressed(b) {
+			gp.PressButton(b)
+		} else {
+			gp.ReleaseButton(b)
+		}
+	}
+}
@@ -10,6 +10,7 @@ require (
 )
 
 require (
+	github.com/CB2Moon/vgamepad-go v0.1.1 // indirect
 	github.com/creack/goselect v0.1.2 // indirect
 	golang.org/x/sys v0.19.0 // indirect
 )
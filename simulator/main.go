@@ -47,6 +47,21 @@ func sendDUML(port serial.Port, sourceAddress, targetAddress, commandType, comma
 	return nil
 }
 
+// replyDUML sends a DUML reply that carries the sequence number of the request it answers
+func replyDUML(port serial.Port, seq uint16, sourceAddress, targetAddress, commandType, commandSet, commandID byte, payload []byte) error {
+	if port == nil {
+		return fmt.Errorf("serial port is nil")
+	}
+
+	packet, err := helper.BuildDUML(seq, sourceAddress, targetAddress, commandType, commandSet, commandID, payload)
+	if err != nil {
+		return err
+	}
+
+	_, err = port.Write(packet)
+	return err
+}
+
 // parseDUMLPacket parses a DUML packet and returns its components
 func parseDUMLPacket(packet []byte) (sourceAddr, targetAddr, cmdType, cmdSet, cmdID byte, payload []byte, err error) {
 	if len(packet) < 13 {
@@ -212,12 +227,12 @@ func main() {
 	log.Println("Shutting down...")
 }
 
-// handleStickDataRequest processes stick data requests
-func handleStickDataRequest(port serial.Port) error {
+// handleStickDataRequest processes stick data requests, echoing the request's sequence number
+func handleStickDataRequest(port serial.Port, seq uint16) error {
 	t := float64(time.Now().UnixNano()) / 1e9
 	rightH, rightV, leftV, leftH, camera := generateMotion(t)
 	stickData := createStickDataPacket(rightH, rightV, leftV, leftH, camera)
-	return sendDUML(port, 0x06, 0x0a, 0x40, 0x06, 0x01, stickData)
+	return replyDUML(port, seq, 0x06, 0x0a, 0x40, 0x06, 0x01, stickData)
 }
 
 func processLoop() {
@@ -225,7 +240,7 @@ func processLoop() {
 
 	for {
 		// Read packet header
-		packetBuffer, packetLength, err := helper.ReadPacketHeader(port, buffer)
+		packetBuffer, packetLength, err := helper.ReadPacketHeader(port, buffer, 0)
 		if err != nil {
 			if verbose {
 				log.Printf("Header read error: %v", err)
@@ -236,7 +251,7 @@ func processLoop() {
 		// Read packet body
 		remainingBytes := int(packetLength) - 4
 		if remainingBytes > 0 {
-			n, err := helper.ReadBytes(port, buffer, remainingBytes)
+			n, err := helper.ReadBytes(port, buffer, remainingBytes, 0)
 			if err != nil || n != remainingBytes {
 				if verbose {
 					log.Printf("Body read error: %v", err)
@@ -251,6 +266,7 @@ func processLoop() {
 			log.Printf("Error parsing packet: %v", err)
 			continue
 		}
+		seq := binary.LittleEndian.Uint16(packetBuffer[6:8])
 
 		// Process commands
 		if cmdType == 0x40 && cmdSet == 0x06 {
@@ -259,7 +275,7 @@ func processLoop() {
 				if verbose {
 					log.Println("Received channel values request, sending stick data")
 				}
-				if err := handleStickDataRequest(port); err != nil {
+				if err := handleStickDataRequest(port, seq); err != nil {
 					log.Printf("Error sending stick data: %v", err)
 				}
 			case 0x24:
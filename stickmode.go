@@ -0,0 +1,27 @@
+package main
+
+import (
+	"sync"
+
+	helper "github.com/CB2Moon/DJI_RC_Nx_Translator/pkg"
+)
+
+var (
+	stickMode      = helper.StickMode2
+	stickModeMutex sync.Mutex // guards stickMode
+)
+
+// useStickMode applies the -stick-mode option
+func useStickMode(mode helper.StickMode) {
+	stickModeMutex.Lock()
+	stickMode = mode
+	stickModeMutex.Unlock()
+	uiLogger("Stick mode: %v", mode)
+}
+
+// currentStickMode returns the stick layout channels are remapped from
+func currentStickMode() helper.StickMode {
+	stickModeMutex.Lock()
+	defer stickModeMutex.Unlock()
+	return stickMode
+}
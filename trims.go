@@ -0,0 +1,85 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	helper "github.com/CB2Moon/DJI_RC_Nx_Translator/pkg"
+)
+
+// trimSaveDelay waits for the pilot to finish adjusting before the profile is written
+const trimSaveDelay = 2 * time.Second
+
+var (
+	trimSaveTimer *time.Timer
+	trimSaveMutex sync.Mutex // guards trimSaveTimer
+)
+
+// reportTrims logs the trims of the given axes
+func reportTrims(mapper *helper.Mapper, axes []string) {
+	trims := mapper.Trims()
+	for _, axis := range axes {
+		uiLogger("Trim %s: %+d (%+.1f%%)", axis, trims[axis], helper.TrimPercent(trims[axis]))
+	}
+}
+
+// logProfileTrims lists the non-zero trims of a profile
+func logProfileTrims(p *helper.Profile) {
+	axes := make([]string, 0, len(p.Trims))
+	for axis, v := range p.Trims {
+		if v != 0 {
+			axes = append(axes, axis)
+		}
+	}
+	sort.Strings(axes)
+	for _, axis := range axes {
+		uiLogger("Trim %s: %+d (%+.1f%%)", axis, p.Trims[axis], helper.TrimPercent(p.Trims[axis]))
+	}
+}
+
+// scheduleTrimSave saves the mapper's trims into its profile once they stop changing
+func scheduleTrimSave(mapper *helper.Mapper) {
+	trimSaveMutex.Lock()
+	defer trimSaveMutex.Unlock()
+	if trimSaveTimer != nil {
+		trimSaveTimer.Stop()
+	}
+	path := profileSavePath(mapper.Profile())
+	trimSaveTimer = time.AfterFunc(trimSaveDelay, func() {
+		saveTrims(path, mapper.Trims())
+	})
+}
+
+// saveTrims stores trims in the loaded profile saved to path and writes it to that file. The
+// path identifies the profile even after an earlier save replaced it in the list.
+func saveTrims(path string, trims map[string]int16) {
+	profileMutex.Lock()
+	var updated *helper.Profile
+	for i, p := range profiles {
+		if profileSavePath(p) == path {
+			copied := *p
+			copied.Trims = trims
+			copied.Path = path
+			profiles[i] = &copied
+			updated = &copied
+			break
+		}
+	}
+	profileMutex.Unlock()
+	if updated == nil {
+		return
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		uiLogger("Error saving trims: %v", err)
+		return
+	}
+	if err := updated.Save(path); err != nil {
+		uiLogger("Error saving trims: %v", err)
+		return
+	}
+	uiLogger("Trims saved to %s", path)
+}